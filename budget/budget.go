@@ -0,0 +1,202 @@
+// Package budget caps the estimated spend of clients (Client.GetCost) per client, tenant and time window,
+// and keeps a ledger of every request for reports
+package budget
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose requests are charged to tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant of ctx, "" if none
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// Cap limits the estimated spend of the requests it matches within Window. empty filters match everything
+type Cap struct {
+	Client     string
+	Tenant     string
+	EachTenant bool          // applies to each tenant separately instead of their total
+	Amount     float64       // in the currency of GetCost
+	Window     time.Duration // rolling window, 0 for all time
+}
+
+func (c Cap) matches(client, tenant string) bool {
+	return (c.Client == "" || c.Client == client) && (c.Tenant == "" || c.Tenant == tenant)
+}
+
+// ExceededError is the cause of ErrBudgetExceeded errors
+type ExceededError struct {
+	Cap   Cap
+	Spent float64 // within the window of the cap
+	Cost  float64 // of the rejected request
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("spent %.4f of %.4f, the request costs %.4f", e.Spent, e.Cap.Amount, e.Cost)
+}
+
+// Record is a request in the ledger
+type Record struct {
+	Time             time.Time
+	Client           string
+	Tenant           string
+	Op               string
+	Characters       int     // as counted by GetCharCount
+	BilledCharacters int     // as reported by the provider, 0 if unknown
+	Cost             float64 // estimated by GetCost, re-priced from BilledCharacters once they are known
+	Unestimated      bool    // a document GetCharCount can't count, its cost is unknown
+}
+
+// Totals sums records
+type Totals struct {
+	Requests         int
+	Characters       int
+	BilledCharacters int
+	Cost             float64
+}
+
+// Budget enforces caps and keeps the ledger, it is safe for concurrent use.
+// the cost of a request is reserved before it is sent and given back if it fails
+type Budget struct {
+	mu      sync.Mutex
+	caps    []Cap
+	records []*Record
+	now     func() time.Time
+}
+
+func New(caps ...Cap) *Budget {
+	return &Budget{caps: caps, now: time.Now}
+}
+
+// ErrUnestimated is the cause of the ErrBudgetExceeded errors of documents a cap applies to but whose cost can't be estimated
+var ErrUnestimated = errors.New("the cost of the document can't be estimated")
+
+// reserve adds a record for the request if it fits in every cap
+func (b *Budget) reserve(r Record) (*Record, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r.Time = b.now()
+
+	for _, c := range b.caps {
+		if !c.matches(r.Client, r.Tenant) {
+			continue
+		}
+		if r.Unestimated {
+			return nil, serr.New(serr.ErrBudgetExceeded, r.Op, r.Client, ErrUnestimated)
+		}
+		spent := b.spent(c, r.Tenant, r.Time)
+		if spent+r.Cost > c.Amount {
+			return nil, serr.New(serr.ErrBudgetExceeded, r.Op, r.Client, &ExceededError{Cap: c, Spent: spent, Cost: r.Cost})
+		}
+	}
+	rec := &r
+	b.records = append(b.records, rec)
+	return rec, nil
+}
+
+func (b *Budget) spent(c Cap, tenant string, now time.Time) float64 {
+	total := 0.0
+	for _, r := range b.records {
+		if c.Window > 0 && !r.Time.After(now.Add(-c.Window)) {
+			continue
+		}
+		if c.matches(r.Client, r.Tenant) && (!c.EachTenant || r.Tenant == tenant) {
+			total += r.Cost
+		}
+	}
+	return total
+}
+
+// cancel removes the record of a failed request
+func (b *Budget) cancel(rec *Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i := slices.Index(b.records, rec); i >= 0 {
+		b.records = slices.Delete(b.records, i, i+1)
+	}
+}
+
+// billed records the characters billed for a request and re-prices it at the estimated price per character
+func (b *Budget) billed(rec *Record, chars int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec.BilledCharacters = chars
+	if chars > 0 && rec.Characters > 0 {
+		rec.Cost = rec.Cost * float64(chars) / float64(rec.Characters)
+	}
+}
+
+// Spend sums the records matching the client and tenant ("" for all) since a time (zero for all time)
+func (b *Budget) Spend(client, tenant string, since time.Time) Totals {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var t Totals
+	for _, r := range b.records {
+		if r.Time.Before(since) || (client != "" && r.Client != client) || (tenant != "" && r.Tenant != tenant) {
+			continue
+		}
+		t.Requests++
+		t.Characters += r.Characters
+		t.BilledCharacters += r.BilledCharacters
+		t.Cost += r.Cost
+	}
+	return t
+}
+
+// Records returns a copy of the ledger, oldest first
+func (b *Budget) Records() []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	records := make([]Record, len(b.records))
+	for i, r := range b.records {
+		records[i] = *r
+	}
+	return records
+}
+
+// Prune removes the records older than before, caps don't see them anymore
+func (b *Budget) Prune(before time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = slices.DeleteFunc(b.records, func(r *Record) bool { return r.Time.Before(before) })
+}
+
+// WriteCSV exports the ledger with a header row, times are RFC 3339 in UTC
+func (b *Budget) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "client", "tenant", "op", "characters", "billed_characters", "cost"})
+	for _, r := range b.Records() {
+		cw.Write([]string{
+			r.Time.UTC().Format(time.RFC3339),
+			r.Client,
+			r.Tenant,
+			r.Op,
+			strconv.Itoa(r.Characters),
+			strconv.Itoa(r.BilledCharacters),
+			strconv.FormatFloat(r.Cost, 'f', -1, 64),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return serr.New(serr.ErrIO, "WriteCSV", "", err)
+	}
+	return nil
+}
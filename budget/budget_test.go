@@ -0,0 +1,105 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+func TestCaps(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		cap     Cap
+		spent   []Record // recorded at start
+		request Record   // at start + after
+		after   time.Duration
+		ok      bool
+	}{
+		"under the cap":         {Cap{Amount: 10}, []Record{{Cost: 4}}, Record{Cost: 6}, 0, true},
+		"over the cap":          {Cap{Amount: 10}, []Record{{Cost: 4}}, Record{Cost: 7}, 0, false},
+		"other client":          {Cap{Client: "deepl", Amount: 10}, []Record{{Client: "deepl", Cost: 10}}, Record{Client: "google", Cost: 5}, 0, true},
+		"same client":           {Cap{Client: "deepl", Amount: 10}, []Record{{Client: "deepl", Cost: 10}}, Record{Client: "deepl", Cost: 5}, 0, false},
+		"tenant":                {Cap{Tenant: "acme", Amount: 10}, []Record{{Tenant: "acme", Cost: 8}}, Record{Tenant: "acme", Cost: 5}, 0, false},
+		"tenants share the cap": {Cap{Amount: 10}, []Record{{Tenant: "acme", Cost: 8}}, Record{Tenant: "globex", Cost: 5}, 0, false},
+		"each tenant":           {Cap{EachTenant: true, Amount: 10}, []Record{{Tenant: "acme", Cost: 8}}, Record{Tenant: "globex", Cost: 5}, 0, true},
+		"inside the window":     {Cap{Amount: 10, Window: time.Hour}, []Record{{Cost: 8}}, Record{Cost: 5}, 59 * time.Minute, false},
+		"window passed":         {Cap{Amount: 10, Window: time.Hour}, []Record{{Cost: 8}}, Record{Cost: 5}, time.Hour, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := start
+			b := New(tc.cap)
+			b.now = func() time.Time { return now }
+			for _, r := range tc.spent {
+				b.records = append(b.records, &Record{Time: start, Client: r.Client, Tenant: r.Tenant, Cost: r.Cost})
+			}
+
+			now = now.Add(tc.after)
+			_, err := b.reserve(tc.request)
+			if tc.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var terr *serr.TranslateError
+			var exceeded *ExceededError
+			if !errors.As(err, &terr) || terr.Code != serr.ErrBudgetExceeded || !errors.As(err, &exceeded) {
+				t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+			}
+			if len(b.records) != len(tc.spent) {
+				t.Error("rejected request was recorded")
+			}
+		})
+	}
+}
+
+func TestLedger(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := New()
+	b.now = func() time.Time { return now }
+
+	b.reserve(Record{Client: "deepl", Tenant: "acme", Op: "Translate", Characters: 100, Cost: 0.002})
+	now = now.Add(time.Hour)
+	rec, _ := b.reserve(Record{Client: "deepl", Tenant: "globex", Op: "Translate", Characters: 50, Cost: 0.001})
+	// re-priced at the estimated price per character
+	b.billed(rec, 100)
+
+	if got := b.Spend("deepl", "", time.Time{}); got != (Totals{Requests: 2, Characters: 150, BilledCharacters: 100, Cost: 0.004}) {
+		t.Errorf("unexpected totals %+v", got)
+	}
+	if got := b.Spend("", "acme", time.Time{}); got.Requests != 1 || got.Cost != 0.002 {
+		t.Errorf("unexpected acme totals %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,client,tenant,op,characters,billed_characters,cost\n" +
+		"2025-01-01T12:00:00Z,deepl,acme,Translate,100,0,0.002\n" +
+		"2025-01-01T13:00:00Z,deepl,globex,Translate,50,100,0.002\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	b.Prune(now)
+	if records := b.Records(); len(records) != 1 || records[0].Tenant != "globex" {
+		t.Errorf("unexpected records after pruning %+v", records)
+	}
+}
+
+func TestTenant(t *testing.T) {
+	ctx := context.Background()
+	if Tenant(ctx) != "" {
+		t.Error("expected no tenant")
+	}
+	if Tenant(WithTenant(ctx, "acme")) != "acme" {
+		t.Error("tenant not stored in the context")
+	}
+}
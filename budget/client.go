@@ -0,0 +1,125 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// Middleware charges the requests of a client to b under the name client, "" for the name of the provider.
+// requests that would exceed a cap fail with ErrBudgetExceeded before they are sent
+func (b *Budget) Middleware(client string) provider.Middleware {
+	return func(next provider.SyncClient) provider.SyncClient {
+		return budgetClient{SyncClient: next, budget: b, name: client}
+	}
+}
+
+// MiddlewareAsync charges documents when they are sent, their billed characters are recorded once the job is done
+func (b *Budget) MiddlewareAsync(client string) provider.AsyncMiddleware {
+	return func(next provider.AsyncClient) provider.AsyncClient {
+		return &budgetAsyncClient{AsyncClient: next, budget: b, name: client}
+	}
+}
+
+// jobTTL is how long the record of a document waits for a done status, jobs polled later are not re-priced
+const jobTTL = 24 * time.Hour
+
+// charge reserves the estimated cost of req
+func (b *Budget) charge(ctx context.Context, op, name string, client provider.Client, req provider.Request) (*Record, error) {
+	if name == "" {
+		name = string(client.Name())
+	}
+	chars := client.GetCharCount(req)
+	return b.reserve(Record{
+		Client:      name,
+		Tenant:      Tenant(ctx),
+		Op:          op,
+		Characters:  chars,
+		Cost:        float64(client.GetCost(req)),
+		Unestimated: len(req.Binary) > 0 && chars == 0,
+	})
+}
+
+type budgetClient struct {
+	provider.SyncClient
+	budget *Budget
+	name   string
+}
+
+func (c budgetClient) Unwrap() provider.Client { return c.SyncClient }
+
+func (c budgetClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	rec, err := c.budget.charge(ctx, "Translate", c.name, c, req)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	res, err := c.SyncClient.Translate(ctx, req)
+	if err != nil {
+		c.budget.cancel(rec)
+		return res, err
+	}
+	c.budget.billed(rec, res.BilledCharacters)
+	return res, nil
+}
+
+type budgetAsyncClient struct {
+	provider.AsyncClient
+	budget *Budget
+	name   string
+	jobs   sync.Map // document id -> *Record, until the job is done, failed or older than jobTTL
+}
+
+func (c *budgetAsyncClient) Unwrap() provider.Client { return c.AsyncClient }
+
+func (c *budgetAsyncClient) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
+	rec, err := c.budget.charge(ctx, "AsyncTranslate", c.name, c, req)
+	if err != nil {
+		return provider.AsyncResponse{}, err
+	}
+	res, err := c.AsyncClient.AsyncTranslate(ctx, req)
+	if err != nil {
+		c.budget.cancel(rec)
+		return res, err
+	}
+	c.expire(rec.Time.Add(-jobTTL))
+	c.jobs.Store(res.DocumentID, rec)
+	return res, nil
+}
+
+// expire forgets the jobs sent before a time, their records keep the estimated cost
+func (c *budgetAsyncClient) expire(before time.Time) {
+	c.jobs.Range(func(id, rec any) bool {
+		if rec.(*Record).Time.Before(before) {
+			c.jobs.Delete(id)
+		}
+		return true
+	})
+}
+
+// CheckStatus re-prices the record of a done document from its billed characters, failed documents are not charged.
+// providers may report a failed document with an error as well (DeepL), so the status is checked first
+func (c *budgetAsyncClient) CheckStatus(ctx context.Context, obj provider.AsyncResponse) (provider.JobStatus, error) {
+	status, err := c.AsyncClient.CheckStatus(ctx, obj)
+	if !(status.Done || status.Failed) {
+		return status, err
+	}
+	if rec, ok := c.jobs.LoadAndDelete(obj.DocumentID); ok {
+		if status.Failed {
+			c.budget.cancel(rec.(*Record))
+		} else {
+			c.budget.billed(rec.(*Record), status.BilledCharacters)
+		}
+	}
+	return status, err
+}
+
+// GetResult forgets the job, the result can only be fetched once the document is done
+func (c *budgetAsyncClient) GetResult(ctx context.Context, obj provider.AsyncResponse) (provider.Response, error) {
+	res, err := c.AsyncClient.GetResult(ctx, obj)
+	if err == nil {
+		c.jobs.Delete(obj.DocumentID)
+	}
+	return res, err
+}
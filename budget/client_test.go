@@ -0,0 +1,211 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// pricedClient charges 0.01 per text and 1 per 8 bytes of documents, it bills the characters of the texts or fails with err.
+// like DeepL, it can't estimate PDFs and reports failed document jobs with an error. document jobs end with status
+type pricedClient struct {
+	err    error
+	status provider.JobStatus
+	calls  int
+}
+
+func (c *pricedClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	c.calls++
+	if c.err != nil {
+		return provider.Response{}, c.err
+	}
+	return provider.Response{Text: req.Text, BilledCharacters: c.GetCharCount(req)}, nil
+}
+
+func (c *pricedClient) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
+	c.calls++
+	return provider.AsyncResponse{DocumentID: fmt.Sprintf("doc-%d", c.calls)}, nil
+}
+
+func (c *pricedClient) CheckStatus(ctx context.Context, obj provider.AsyncResponse) (provider.JobStatus, error) {
+	if c.status.Failed {
+		return c.status, serr.New(serr.ErrProviderAPI, "CheckStatus", "priced", errors.New("translation failed"))
+	}
+	return c.status, nil
+}
+
+func (c *pricedClient) GetResult(ctx context.Context, obj provider.AsyncResponse) (provider.Response, error) {
+	return provider.Response{}, nil
+}
+
+func (c *pricedClient) GetCost(req provider.Request) float32 {
+	if len(req.Binary) > 0 {
+		return float32(c.GetCharCount(req)) / 8
+	}
+	return 0.01 * float32(len(req.Text))
+}
+
+func (c *pricedClient) GetCharCount(req provider.Request) int {
+	if strings.HasSuffix(req.FileName, ".pdf") {
+		return 0
+	}
+	n := len(req.Binary)
+	for _, t := range req.Text {
+		n += len(t)
+	}
+	return n
+}
+
+func (c *pricedClient) Capabilities() provider.Capabilities { return provider.Capabilities{} }
+func (c *pricedClient) Name() provider.Provider             { return "priced" }
+func (c *pricedClient) Version() string                     { return "test" }
+
+func TestMiddleware(t *testing.T) {
+	b := New(Cap{EachTenant: true, Amount: 0.025})
+	inner := &pricedClient{}
+	client := b.Middleware("")(inner)
+	acme := WithTenant(context.Background(), "acme")
+	req := provider.Request{Text: []string{"hello"}}
+
+	for range 2 {
+		if _, err := client.Translate(acme, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := client.Translate(acme, req)
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrBudgetExceeded || terr.Provider != "priced" {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("rejected request reached the client")
+	}
+	if _, err := client.Translate(WithTenant(context.Background(), "globex"), req); err != nil {
+		t.Errorf("other tenants have their own cap: %v", err)
+	}
+
+	spend := b.Spend("priced", "acme", b.now().AddDate(0, 0, -1))
+	if spend.Requests != 2 || spend.Characters != 10 || spend.BilledCharacters != 10 {
+		t.Errorf("unexpected spend %+v", spend)
+	}
+}
+
+func TestMiddlewareFailure(t *testing.T) {
+	b := New()
+	inner := &pricedClient{err: serr.New(serr.ErrNetwork, "Translate", "priced", errors.New("timeout"))}
+	if _, err := b.Middleware("deepl-pro")(inner).Translate(context.Background(), provider.Request{Text: []string{"hello"}}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(b.Records()) != 0 {
+		t.Error("failed requests should not be charged")
+	}
+}
+
+func TestMiddlewareAsync(t *testing.T) {
+	cases := map[string]struct {
+		status  provider.JobStatus
+		records []Record
+	}{
+		// the estimate is re-priced from the billed characters
+		"done":    {provider.JobStatus{Done: true, BilledCharacters: 16}, []Record{{Characters: 8, BilledCharacters: 16, Cost: 2}}},
+		"failed":  {provider.JobStatus{Failed: true}, nil},
+		"running": {provider.JobStatus{SecondsRemaining: 5}, []Record{{Characters: 8, Cost: 1}}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := New()
+			inner := &pricedClient{status: tc.status}
+			client := provider.Wrap(inner, b.Middleware("deepl-pro"), b.MiddlewareAsync("deepl-pro")).(provider.AsyncClient)
+			ctx := context.Background()
+
+			res, err := client.AsyncTranslate(ctx, provider.Request{Binary: []byte("12345678"), FileName: "a.txt"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.CheckStatus(ctx, res); (err != nil) != tc.status.Failed {
+				t.Fatalf("got %v, want an error %v", err, tc.status.Failed)
+			}
+
+			var got []Record
+			for _, r := range b.Records() {
+				if r.Client != "deepl-pro" || r.Op != "AsyncTranslate" {
+					t.Errorf("unexpected record %+v", r)
+				}
+				got = append(got, Record{Characters: r.Characters, BilledCharacters: r.BilledCharacters, Cost: r.Cost})
+			}
+			if !reflect.DeepEqual(got, tc.records) {
+				t.Errorf("got %+v, want %+v", got, tc.records)
+			}
+		})
+	}
+}
+
+func TestMiddlewareUnestimated(t *testing.T) {
+	req := provider.Request{Binary: []byte("%PDF-1.7"), FileName: "a.pdf"}
+	ctx := context.Background()
+
+	capped := New(Cap{Client: "deepl-pro", Amount: 100})
+	inner := &pricedClient{}
+	_, err := capped.MiddlewareAsync("deepl-pro")(inner).AsyncTranslate(ctx, req)
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrBudgetExceeded || !errors.Is(err, ErrUnestimated) {
+		t.Fatalf("documents without an estimate should not pass a cap, got %v", err)
+	}
+	if inner.calls != 0 {
+		t.Error("rejected document reached the client")
+	}
+
+	uncapped := New(Cap{Client: "other", Amount: 100})
+	if _, err := uncapped.MiddlewareAsync("deepl-pro")(inner).AsyncTranslate(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if records := uncapped.Records(); len(records) != 1 || !records[0].Unestimated {
+		t.Errorf("the record should be flagged, got %+v", records)
+	}
+}
+
+func TestMiddlewareJobs(t *testing.T) {
+	b := New()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	client := b.MiddlewareAsync("")(&pricedClient{}).(*budgetAsyncClient)
+	ctx := context.Background()
+	req := provider.Request{Binary: []byte("12345678"), FileName: "a.txt"}
+	jobs := func() int {
+		n := 0
+		client.jobs.Range(func(any, any) bool { n++; return true })
+		return n
+	}
+
+	res, _ := client.AsyncTranslate(ctx, req)
+	if _, err := client.GetResult(ctx, res); err != nil || jobs() != 0 {
+		t.Errorf("fetched documents should be forgotten, %d jobs left", jobs())
+	}
+
+	client.AsyncTranslate(ctx, req)
+	now = now.Add(jobTTL + time.Second)
+	client.AsyncTranslate(ctx, req)
+	if jobs() != 1 {
+		t.Errorf("stale jobs should expire, got %d", jobs())
+	}
+}
+
+func TestMiddlewareUnwrap(t *testing.T) {
+	b := New()
+	inner := &pricedClient{}
+	client := provider.Wrap(inner, b.Middleware(""), b.MiddlewareAsync(""))
+	if found, ok := provider.As[*pricedClient](client); !ok || found != inner {
+		t.Errorf("the budget clients should unwrap to the wrapped client, got %v", found)
+	}
+	if found, ok := provider.As[*pricedClient](b.MiddlewareAsync("")(inner)); !ok || found != inner {
+		t.Errorf("the async budget client should unwrap to the wrapped client, got %v", found)
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+var boltBucket = []byte("translations")
+
+// Bolt is an on-disk Cache stored in a bbolt database file, entries survive restarts
+type Bolt struct {
+	db  *bolt.DB
+	ttl time.Duration
+	now func() time.Time
+
+	hits, misses atomic.Int64
+}
+
+type boltItem struct {
+	Entry   Entry
+	Expires time.Time `json:",omitzero"`
+}
+
+// OpenBolt opens or creates the cache database at path, entries expire after ttl (0 for never).
+// a database can only be opened by one process at a time, Close releases it
+func OpenBolt(path string, ttl time.Duration) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, serr.New(serr.ErrIO, "OpenBolt", "", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, serr.New(serr.ErrIO, "OpenBolt", "", err)
+	}
+	return &Bolt{db: db, ttl: ttl, now: time.Now}, nil
+}
+
+func (c *Bolt) Get(key string) (Entry, bool, error) {
+	var item boltItem
+	found := false
+	err := c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &item)
+	})
+	if err != nil {
+		c.misses.Add(1)
+		return Entry{}, false, serr.New(serr.ErrIO, "Get", "", err)
+	}
+	if !found {
+		c.misses.Add(1)
+		return Entry{}, false, nil
+	}
+	if !item.Expires.IsZero() && !c.now().Before(item.Expires) {
+		c.misses.Add(1)
+		return Entry{}, false, c.delete(key)
+	}
+	c.hits.Add(1)
+	return item.Entry, true, nil
+}
+
+func (c *Bolt) Set(key string, e Entry) error {
+	item := boltItem{Entry: e}
+	if c.ttl > 0 {
+		item.Expires = c.now().Add(c.ttl)
+	}
+	v, err := json.Marshal(item)
+	if err != nil {
+		return serr.New(serr.ErrIO, "Set", "", err)
+	}
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), v)
+	})
+	if err != nil {
+		return serr.New(serr.ErrIO, "Set", "", err)
+	}
+	return nil
+}
+
+func (c *Bolt) delete(key string) error {
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return serr.New(serr.ErrIO, "Get", "", err)
+	}
+	return nil
+}
+
+// Prune deletes the expired entries, they are otherwise only deleted when looked up
+func (c *Bolt) Prune() error {
+	now := c.now()
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var item boltItem
+			if err := json.Unmarshal(v, &item); err != nil || (!item.Expires.IsZero() && !now.Before(item.Expires)) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// keys can't be deleted while iterating
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return serr.New(serr.ErrIO, "Prune", "", err)
+	}
+	return nil
+}
+
+func (c *Bolt) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+func (c *Bolt) Close() error {
+	if err := c.db.Close(); err != nil {
+		return serr.New(serr.ErrIO, "Close", "", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+func TestBolt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := OpenBolt(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("a", Entry{Text: "A", DetectedLanguage: lang.English}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// entries survive reopening
+	c, err = OpenBolt(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	e, ok, err := c.Get("a")
+	if err != nil || !ok || e.Text != "A" || e.DetectedLanguage != lang.English {
+		t.Fatalf("got %+v %v %v", e, ok, err)
+	}
+	if _, ok, _ := c.Get("b"); ok {
+		t.Error("unexpected entry")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestBoltExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, err := OpenBolt(filepath.Join(t.TempDir(), "cache.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", Entry{Text: "A"})
+	c.Set("b", Entry{Text: "B"})
+	if _, ok, _ := c.Get("a"); !ok {
+		t.Fatal("entry expired too early")
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok, _ := c.Get("a"); ok {
+		t.Fatal("entry should have expired")
+	}
+	if err := c.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.db.View(func(tx *bolt.Tx) error {
+		if n := tx.Bucket(boltBucket).Stats().KeyN; n != 0 {
+			t.Errorf("%d expired entries not pruned", n)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,62 @@
+// Package cache stores translations per segment so repeated texts (UI strings, subtitle lines) are only translated once.
+// Wrap a client with a Cache backend: NewLRU in memory or OpenBolt on disk
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// Entry is the cached translation of a segment
+type Entry struct {
+	Text             string
+	DetectedLanguage lang.Language
+}
+
+// Stats counts the lookups of a cache. expired entries count as misses
+type Stats struct {
+	Hits   int64
+	Misses int64
+}
+
+// HitRate returns the share of lookups that were hits, 0 before any lookup
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Cache stores entries by key. entries expire after the TTL given to the backend.
+// implementations must be safe for concurrent use
+type Cache interface {
+	Get(key string) (Entry, bool, error)
+	Set(key string, e Entry) error
+	Stats() Stats
+	Close() error
+}
+
+// Normalize returns the text a segment is cached under, surrounding whitespace is kept out of the translation
+func Normalize(text string) string {
+	return strings.TrimSpace(text)
+}
+
+// Key returns the cache key of a normalized segment translated by client with the settings of req
+func Key(client provider.Client, req provider.Request, text string) string {
+	// only the fields changing the translation of a segment
+	b, _ := json.Marshal(struct {
+		Provider   provider.Provider
+		Version    string
+		From, To   lang.Language
+		GlossaryID string
+		Options    provider.Options
+		Text       string
+	}{client.Name(), client.Version(), req.From, req.To, req.GlossaryID, req.Options, text})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
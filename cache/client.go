@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	segtext "github.com/o0n1x/sublate-go/internal/segtext"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// Client is a SyncClient answering text segments from a Cache, only the segments missing from it are sent to the wrapped client.
+// document requests are not cached
+type Client struct {
+	provider.SyncClient
+	cache Cache
+}
+
+func (c *Client) Unwrap() provider.Client { return c.SyncClient }
+
+// Wrap returns client with its text translations cached in c. the result is also an AsyncClient when client is one
+func Wrap(client provider.SyncClient, c Cache) provider.SyncClient {
+	return provider.Wrap(client, Middleware(c), nil).(provider.SyncClient)
+}
+
+// Middleware returns the Client of c as a provider.Middleware, ex: to chain it with other middlewares
+func Middleware(c Cache) provider.Middleware {
+	return func(next provider.SyncClient) provider.SyncClient {
+		return &Client{SyncClient: next, cache: c}
+	}
+}
+
+// Translate translates the segments missing from the cache in one request, identical segments are only sent once.
+// cache failures are treated as misses, the translation doesn't fail because of them
+func (c *Client) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	if req.ReqType == sformat.File || len(req.Binary) > 0 {
+		return c.SyncClient.Translate(ctx, req)
+	}
+
+	res := provider.Response{
+		Text:     make([]string, len(req.Text)),
+		Segments: make([]provider.SegmentMeta, len(req.Text)),
+		Provider: c.Name(),
+	}
+	missing := map[string][]int{} // key -> indexes of the segments
+	var keys []string             // keys of the missing segments in request order
+	sub := req
+	sub.Text = nil
+	for i, text := range req.Text {
+		norm := Normalize(text)
+		if norm == "" {
+			res.Text[i] = text
+			continue
+		}
+		key := Key(c, req, norm)
+		if _, ok := missing[key]; ok {
+			missing[key] = append(missing[key], i)
+			continue
+		}
+		if e, ok, err := c.cache.Get(key); err == nil && ok {
+			res.Text[i] = segtext.Rewrap(text, e.Text)
+			res.Segments[i].DetectedLanguage = e.DetectedLanguage
+			continue
+		}
+		keys = append(keys, key)
+		sub.Text = append(sub.Text, norm)
+		missing[key] = []int{i}
+	}
+	if len(sub.Text) == 0 {
+		return res, nil
+	}
+
+	translated, err := c.SyncClient.Translate(ctx, sub)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	if len(translated.Text) != len(sub.Text) {
+		return provider.Response{}, serr.New(serr.ErrInvalidResponse, "Translate", string(c.Name()), fmt.Errorf("got %d translations for %d texts", len(translated.Text), len(sub.Text)))
+	}
+
+	for j, key := range keys {
+		e := Entry{Text: Normalize(translated.Text[j])}
+		var seg provider.SegmentMeta
+		if j < len(translated.Segments) {
+			seg = translated.Segments[j]
+			e.DetectedLanguage = seg.DetectedLanguage
+		}
+		c.cache.Set(key, e)
+
+		for n, i := range missing[key] {
+			res.Text[i] = segtext.Rewrap(req.Text[i], e.Text)
+			res.Segments[i].DetectedLanguage = seg.DetectedLanguage
+			if n == 0 {
+				// the duplicates were not billed
+				res.Segments[i].BilledCharacters = seg.BilledCharacters
+			}
+		}
+	}
+	res.BilledCharacters = translated.BilledCharacters
+	res.Model = translated.Model
+	if translated.Provider != "" {
+		res.Provider = translated.Provider
+	}
+	return res, nil
+}
+
+// Stats returns the stats of the cache
+func (c *Client) Stats() Stats {
+	return c.cache.Stats()
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// docClient is a fake.Upper that also translates documents
+type docClient struct {
+	fake.Upper
+}
+
+func (c *docClient) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
+	return provider.AsyncResponse{DocumentID: "doc"}, nil
+}
+
+func (c *docClient) CheckStatus(ctx context.Context, obj provider.AsyncResponse) (provider.JobStatus, error) {
+	return provider.JobStatus{Done: true}, nil
+}
+
+func (c *docClient) GetResult(ctx context.Context, obj provider.AsyncResponse) (provider.Response, error) {
+	return provider.Response{}, nil
+}
+
+func TestClient(t *testing.T) {
+	inner := &fake.Upper{}
+	client := Wrap(inner, NewLRU(0, 0))
+	ctx := context.Background()
+	req := provider.Request{To: lang.German}
+
+	cases := []struct { // in order, each step sees the cache of the previous ones
+		name  string
+		texts []string
+		want  []string
+		sent  []string // texts reaching the wrapped client
+	}{
+		{"all misses", []string{"hello", "world"}, []string{"HELLO", "WORLD"}, []string{"hello", "world"}},
+		{"only misses are sent", []string{"hello", "again", "world"}, []string{"HELLO", "AGAIN", "WORLD"}, []string{"again"}},
+		{"duplicates sent once", []string{"new", "new"}, []string{"NEW", "NEW"}, []string{"new"}},
+		{"whitespace is kept", []string{"  hello\n", ""}, []string{"  HELLO\n", ""}, nil},
+	}
+
+	for _, tc := range cases {
+		inner.Requests = nil
+		r := req
+		r.Text = tc.texts
+		resp, err := client.Translate(ctx, r)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(resp.Text, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, resp.Text, tc.want)
+		}
+		var sent []string
+		for _, r := range inner.Requests {
+			sent = append(sent, r.Text...)
+		}
+		if !reflect.DeepEqual(sent, tc.sent) {
+			t.Errorf("%s: sent %q, want %q", tc.name, sent, tc.sent)
+		}
+	}
+
+	// other settings are cached separately
+	inner.Requests = nil
+	r := req
+	r.Text = []string{"hello"}
+	r.To = lang.French
+	if _, err := client.Translate(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.Requests) != 1 {
+		t.Error("a translation to another language was served from the cache")
+	}
+
+	if s := client.(*Client).Stats(); s.Hits != 3 || s.Misses != 5 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestWrapKeepsAsync(t *testing.T) {
+	if _, ok := Wrap(&fake.Upper{}, NewLRU(0, 0)).(provider.AsyncClient); ok {
+		t.Error("a sync only client should stay sync only")
+	}
+	client := Wrap(&docClient{}, NewLRU(0, 0))
+	if _, ok := client.(provider.AsyncClient); !ok {
+		t.Error("document support of the wrapped client was lost")
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LRU is an in-memory Cache evicting the least recently used entries
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used
+	now     func() time.Time
+
+	hits, misses atomic.Int64
+}
+
+type lruItem struct {
+	key     string
+	entry   Entry
+	expires time.Time // zero when the entry doesn't expire
+}
+
+// NewLRU returns a cache holding up to size entries (0 for no limit) that expire after ttl (0 for never)
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *LRU) Get(key string) (Entry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return Entry{}, false, nil
+	}
+	item := el.Value.(*lruItem)
+	if !item.expires.IsZero() && !c.now().Before(item.expires) {
+		c.remove(el)
+		c.misses.Add(1)
+		return Entry{}, false, nil
+	}
+	c.order.MoveToFront(el)
+	c.hits.Add(1)
+	return item.entry, true, nil
+}
+
+func (c *LRU) Set(key string, e Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = c.now().Add(c.ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = &lruItem{key, e, expires}
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&lruItem{key, e, expires})
+	if c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruItem).key)
+}
+
+// Len returns the number of entries, including expired ones not looked up yet
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+func (c *LRU) Close() error {
+	return nil
+}
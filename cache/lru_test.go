@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Set("a", Entry{Text: "A"})
+	c.Set("b", Entry{Text: "B"})
+	c.Get("a") // b is now the least recently used
+	c.Set("c", Entry{Text: "C"})
+
+	cases := map[string]struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		"recently used": {"a", "A", true},
+		"evicted":       {"b", "", false},
+		"newest":        {"c", "C", true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e, ok, err := c.Get(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.ok || e.Text != tc.want {
+				t.Errorf("got %q %v, want %q %v", e.Text, ok, tc.want, tc.ok)
+			}
+		})
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(0, time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", Entry{Text: "A"})
+	if _, ok, _ := c.Get("a"); !ok {
+		t.Fatal("entry expired too early")
+	}
+	now = now.Add(time.Hour)
+	if _, ok, _ := c.Get("a"); ok {
+		t.Fatal("entry should have expired")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expired entry not removed")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.HitRate() != 0.5 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
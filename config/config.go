@@ -0,0 +1,396 @@
+// Package config loads the clients of a service from a YAML, JSON or TOML file and environment variables.
+//
+//	default: deepl-pro
+//	clients:
+//	  deepl-pro:
+//	    provider: DeepL
+//	    api_key: ${DEEPL_API_KEY}
+//	    timeout: 30s
+//	    rate_limit:
+//	      requests: 5
+//	      characters: 100000
+//	      characters_per: 1m
+//	retry:
+//	  max_attempts: 3
+//	  backoff: 1s
+//	concurrency: 4
+//	routes:
+//	  - client: deepl-pro
+//	    to: [DE, FR]
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	provider "github.com/o0n1x/sublate-go/provider"
+	translator "github.com/o0n1x/sublate-go/translator"
+
+	// built-in providers
+	_ "github.com/o0n1x/sublate-go/provider/deepl"
+)
+
+// file formats accepted by Parse
+const (
+	YAML = "yaml"
+	JSON = "json"
+	TOML = "toml"
+)
+
+// EnvPrefix prefixes the environment variables overriding a config. see ApplyEnv
+const EnvPrefix = "SUBLATE_"
+
+type Config struct {
+	Default     string            `yaml:"default" json:"default" toml:"default"`             // client used when no route matches
+	Clients     map[string]Client `yaml:"clients" json:"clients" toml:"clients"`             // instance name -> client
+	Retry       Retry             `yaml:"retry" json:"retry" toml:"retry"`                   // applied to every client by Build
+	Concurrency int               `yaml:"concurrency" json:"concurrency" toml:"concurrency"` // max requests in flight in a batch, 0 for sequential. see BatchOptions
+	Routes      []Route           `yaml:"routes" json:"routes" toml:"routes"`                // first matching route wins
+}
+
+type Client struct {
+	Provider  provider.Provider `yaml:"provider" json:"provider" toml:"provider"`
+	APIKey    string            `yaml:"api_key" json:"api_key" toml:"api_key"`
+	BaseURL   string            `yaml:"base_url" json:"base_url" toml:"base_url"`
+	Timeout   Duration          `yaml:"timeout" json:"timeout" toml:"timeout"`
+	UserAgent string            `yaml:"user_agent" json:"user_agent" toml:"user_agent"`
+	Headers   map[string]string `yaml:"headers" json:"headers" toml:"headers"`
+	RateLimit RateLimit         `yaml:"rate_limit" json:"rate_limit" toml:"rate_limit"`
+}
+
+// RateLimit throttles a client, see provider.RateLimit. the periods default to a second
+type RateLimit struct {
+	Requests      int      `yaml:"requests" json:"requests" toml:"requests"`
+	RequestsPer   Duration `yaml:"requests_per" json:"requests_per" toml:"requests_per"`
+	Characters    int      `yaml:"characters" json:"characters" toml:"characters"`
+	CharactersPer Duration `yaml:"characters_per" json:"characters_per" toml:"characters_per"`
+}
+
+type Retry struct {
+	MaxAttempts int      `yaml:"max_attempts" json:"max_attempts" toml:"max_attempts"` // 0 or 1 disables retries
+	Backoff     Duration `yaml:"backoff" json:"backoff" toml:"backoff"`                // wait before the first retry, doubled on each attempt
+	MaxBackoff  Duration `yaml:"max_backoff" json:"max_backoff" toml:"max_backoff"`
+}
+
+// Policy returns the provider.Retry of the retry settings
+func (r Retry) Policy() provider.Retry {
+	return provider.Retry{MaxAttempts: r.MaxAttempts, Backoff: time.Duration(r.Backoff), MaxBackoff: time.Duration(r.MaxBackoff)}
+}
+
+// Duration is a time.Duration written as a string in config files. ex: "30s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Options returns the client options of the client config
+func (c Client) Options() []provider.ClientOption {
+	opts := []provider.ClientOption{provider.WithAPIKey(c.APIKey)}
+	if c.BaseURL != "" {
+		opts = append(opts, provider.WithBaseURL(c.BaseURL))
+	}
+	if c.Timeout > 0 {
+		opts = append(opts, provider.WithTimeout(time.Duration(c.Timeout)))
+	}
+	if c.UserAgent != "" {
+		opts = append(opts, provider.WithUserAgent(c.UserAgent))
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Headers)) {
+		opts = append(opts, provider.WithHeader(key, c.Headers[key]))
+	}
+	if r := c.RateLimit; r.Requests > 0 || r.Characters > 0 {
+		opts = append(opts, provider.WithRateLimit(provider.RateLimit{
+			Requests:   provider.Rate{Count: r.Requests, Per: time.Duration(r.RequestsPer)},
+			Characters: provider.Rate{Count: r.Characters, Per: time.Duration(r.CharactersPer)},
+		}))
+	}
+	return opts
+}
+
+// Load reads a config file, its format is taken from the extension, and applies the environment overrides
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, serr.New(serr.ErrIO, "Load", "", err)
+	}
+	cfg, err := Parse(data, strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Parse decodes a config. ${VAR} references in the string values of the default and the clients are replaced
+// by environment variables once decoded, so keys don't have to be in the file and can hold any character
+func Parse(data []byte, fileFormat string) (*Config, error) {
+	cfg := &Config{}
+	var err error
+	switch fileFormat {
+	case YAML, "yml":
+		err = yaml.Unmarshal(data, cfg)
+	case JSON:
+		err = json.Unmarshal(data, cfg)
+	case TOML:
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return nil, serr.New(serr.ErrInvalidConfig, "Parse", "", fmt.Errorf("unknown config format %q", fileFormat))
+	}
+	if err != nil {
+		return nil, serr.New(serr.ErrInvalidConfig, "Parse", "", err)
+	}
+	cfg.expandEnv(os.Getenv)
+	return cfg, nil
+}
+
+func (c *Config) expandEnv(getenv func(string) string) {
+	expand := func(s *string) {
+		*s = envRef.ReplaceAllStringFunc(*s, func(ref string) string {
+			return getenv(envRef.FindStringSubmatch(ref)[1])
+		})
+	}
+	expand(&c.Default)
+	for name, client := range c.Clients {
+		expand((*string)(&client.Provider))
+		expand(&client.APIKey)
+		expand(&client.BaseURL)
+		expand(&client.UserAgent)
+		for key, value := range client.Headers {
+			expand(&value)
+			client.Headers[key] = value
+		}
+		c.Clients[name] = client
+	}
+}
+
+// ApplyEnv overrides the config with environment variables:
+//
+//	SUBLATE_DEFAULT, SUBLATE_CONCURRENCY, SUBLATE_RETRY_MAX_ATTEMPTS, SUBLATE_RETRY_BACKOFF, SUBLATE_RETRY_MAX_BACKOFF
+//	SUBLATE_<CLIENT>_API_KEY, SUBLATE_<CLIENT>_BASE_URL, SUBLATE_<CLIENT>_TIMEOUT, SUBLATE_<CLIENT>_USER_AGENT
+//
+// where <CLIENT> is the upper cased client name with - and . replaced by _. ex: SUBLATE_DEEPL_PRO_API_KEY
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	var errs []error
+	set := func(name string, apply func(string) error) {
+		if v := getenv(EnvPrefix + name); v != "" {
+			if err := apply(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", EnvPrefix, name, err))
+			}
+		}
+	}
+	setString := func(dst *string) func(string) error {
+		return func(v string) error { *dst = v; return nil }
+	}
+
+	set("DEFAULT", setString(&c.Default))
+	set("CONCURRENCY", parseInt(&c.Concurrency))
+	set("RETRY_MAX_ATTEMPTS", parseInt(&c.Retry.MaxAttempts))
+	set("RETRY_BACKOFF", c.Retry.Backoff.set)
+	set("RETRY_MAX_BACKOFF", c.Retry.MaxBackoff.set)
+
+	for name, client := range c.Clients {
+		prefix := envName(name) + "_"
+		set(prefix+"API_KEY", setString(&client.APIKey))
+		set(prefix+"BASE_URL", setString(&client.BaseURL))
+		set(prefix+"TIMEOUT", client.Timeout.set)
+		set(prefix+"USER_AGENT", setString(&client.UserAgent))
+		set(prefix+"RATE_LIMIT_REQUESTS", parseInt(&client.RateLimit.Requests))
+		set(prefix+"RATE_LIMIT_CHARACTERS", parseInt(&client.RateLimit.Characters))
+		c.Clients[name] = client
+	}
+
+	if len(errs) > 0 {
+		return serr.New(serr.ErrInvalidConfig, "ApplyEnv", "", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (d *Duration) set(v string) error { return d.UnmarshalText([]byte(v)) }
+
+func parseInt(dst *int) func(string) error {
+	return func(v string) error {
+		_, err := fmt.Sscan(v, dst)
+		return err
+	}
+}
+
+func envName(client string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToUpper(client))
+}
+
+// Validate reports every problem of the config at once
+func (c *Config) Validate() error {
+	var errs []error
+	registered := provider.Providers()
+
+	if len(c.Clients) == 0 {
+		errs = append(errs, errors.New("no clients"))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Clients)) {
+		client := c.Clients[name]
+		switch {
+		case client.Provider == "":
+			errs = append(errs, fmt.Errorf("client %q: missing provider", name))
+		case !slices.Contains(registered, client.Provider):
+			errs = append(errs, fmt.Errorf("client %q: unknown provider %q, registered: %v", name, client.Provider, registered))
+		}
+		if client.APIKey == "" {
+			errs = append(errs, fmt.Errorf("client %q: missing api_key", name))
+		}
+		if client.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("client %q: negative timeout", name))
+		}
+		if r := client.RateLimit; r.Requests < 0 || r.Characters < 0 || r.RequestsPer < 0 || r.CharactersPer < 0 {
+			errs = append(errs, fmt.Errorf("client %q: negative rate limit", name))
+		}
+	}
+
+	if _, ok := c.Clients[c.Default]; c.Default != "" && !ok {
+		errs = append(errs, fmt.Errorf("default client %q is not defined", c.Default))
+	}
+	if c.Concurrency < 0 {
+		errs = append(errs, errors.New("negative concurrency"))
+	}
+	if c.Retry.MaxAttempts < 0 || c.Retry.Backoff < 0 || c.Retry.MaxBackoff < 0 {
+		errs = append(errs, errors.New("negative retry settings"))
+	}
+
+	for i, r := range c.Routes {
+		if _, ok := c.Clients[r.Client]; !ok {
+			errs = append(errs, fmt.Errorf("route %d: client %q is not defined", i, r.Client))
+		}
+		for _, f := range r.Formats {
+			if _, ok := lookupFormat(f); !ok {
+				errs = append(errs, fmt.Errorf("route %d: unknown format %q", i, f))
+			}
+		}
+		if r.MaxChars > 0 && r.MinChars > r.MaxChars {
+			errs = append(errs, fmt.Errorf("route %d: min_chars is over max_chars", i))
+		}
+	}
+
+	if len(errs) > 0 {
+		return serr.New(serr.ErrInvalidConfig, "Validate", "", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Build creates every client as a named provider instance, see provider.NewInstance. the clients retry with the retry settings
+func (c *Config) Build() (map[string]provider.Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	clients := make(map[string]provider.Client, len(c.Clients))
+	for name, client := range c.Clients {
+		opts := append(client.Options(), provider.WithRetry(c.Retry.Policy()))
+		built, err := provider.NewInstance(name, client.Provider, opts...)
+		if err != nil {
+			return nil, serr.New(serr.ErrInvalidConfig, "Build", string(client.Provider), fmt.Errorf("client %q: %w", name, err))
+		}
+		clients[name] = built
+	}
+	return clients, nil
+}
+
+// Route sends the requests it matches to a client. empty fields match everything
+type Route struct {
+	Client   string   `yaml:"client" json:"client" toml:"client"`
+	From     []string `yaml:"from" json:"from" toml:"from"`
+	To       []string `yaml:"to" json:"to" toml:"to"`
+	Formats  []string `yaml:"formats" json:"formats" toml:"formats"` // "text" or file types. ex: docx, srt
+	MinChars int      `yaml:"min_chars" json:"min_chars" toml:"min_chars"`
+	MaxChars int      `yaml:"max_chars" json:"max_chars" toml:"max_chars"`
+}
+
+// Matches reports whether the request goes through the route. chars is the character count of the request
+func (r Route) Matches(req provider.Request, chars int) bool {
+	if len(r.From) > 0 && !containsFold(r.From, req.From.String()) {
+		return false
+	}
+	if len(r.To) > 0 && !containsFold(r.To, req.To.String()) {
+		return false
+	}
+	if len(r.Formats) > 0 && !slices.ContainsFunc(r.Formats, func(f string) bool { return formatMatches(f, req) }) {
+		return false
+	}
+	return chars >= r.MinChars && (r.MaxChars == 0 || chars <= r.MaxChars)
+}
+
+// Router returns a client sending requests through the routes then to the default client, clients are the ones returned by Build.
+// the routes see translator.CharCount as the character count of a request
+func (c *Config) Router(clients map[string]provider.Client) *translator.Router {
+	rules := make([]translator.Rule, 0, len(c.Routes)+1)
+	for _, r := range c.Routes {
+		if client, ok := clients[r.Client]; ok {
+			rules = append(rules, translator.Rule{Match: r.Matches, Clients: []provider.Client{client}})
+		}
+	}
+	if client, ok := clients[c.Default]; ok {
+		rules = append(rules, translator.Rule{Clients: []provider.Client{client}})
+	}
+	return translator.NewRouter(rules)
+}
+
+// BatchOptions returns the translator.BatchTranslate options of the config. ex: translator.BatchTranslate(ctx, reqs, client, cfg.BatchOptions()...)
+func (c *Config) BatchOptions() []translator.BatchOption {
+	return []translator.BatchOption{translator.WithConcurrency(c.Concurrency)}
+}
+
+// ClientFor returns the name of the client a request is routed to, the default client if no route matches
+func (c *Config) ClientFor(req provider.Request, chars int) string {
+	for _, r := range c.Routes {
+		if r.Matches(req, chars) {
+			return r.Client
+		}
+	}
+	return c.Default
+}
+
+const textFormat = "text"
+
+func lookupFormat(name string) (sformat.Descriptor, bool) {
+	if strings.EqualFold(name, textFormat) {
+		return sformat.TXT, true
+	}
+	return sformat.ByExtension("." + strings.TrimPrefix(name, "."))
+}
+
+func formatMatches(name string, req provider.Request) bool {
+	isText := req.ReqType == sformat.Text || (req.ReqType == "" && len(req.Binary) == 0)
+	if strings.EqualFold(name, textFormat) {
+		return isText
+	}
+	d, ok := lookupFormat(name)
+	return ok && !isText && sformat.Detect(req.FileName, req.Binary).Name == d.Name
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
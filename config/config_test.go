@@ -0,0 +1,345 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+	translator "github.com/o0n1x/sublate-go/translator"
+)
+
+const yamlConfig = `
+default: deepl-free
+clients:
+  deepl-free:
+    provider: DeepL
+    # the key is set by ${SUBLATE_TEST_KEY}
+    api_key: ${SUBLATE_TEST_KEY}
+    timeout: 30s
+    rate_limit:
+      requests: 5
+      characters: 100000
+      characters_per: 1m
+  deepl-pro:
+    provider: DeepL
+    api_key: pro-key
+    base_url: https://deepl-gateway.internal
+    headers:
+      X-Team: docs
+retry:
+  max_attempts: 3
+  backoff: 500ms
+concurrency: 4
+routes:
+  - client: deepl-pro
+    formats: [docx, pdf]
+  - client: deepl-pro
+    to: [ja]
+    min_chars: 100
+`
+
+const jsonConfig = `{
+  "default": "deepl-free",
+  "clients": {
+    "deepl-free": {"provider": "DeepL", "api_key": "${SUBLATE_TEST_KEY}", "timeout": "30s", "rate_limit": {"requests": 5, "characters": 100000, "characters_per": "1m"}},
+    "deepl-pro": {"provider": "DeepL", "api_key": "pro-key", "base_url": "https://deepl-gateway.internal", "headers": {"X-Team": "docs"}}
+  },
+  "retry": {"max_attempts": 3, "backoff": "500ms"},
+  "concurrency": 4,
+  "routes": [{"client": "deepl-pro", "formats": ["docx", "pdf"]}, {"client": "deepl-pro", "to": ["ja"], "min_chars": 100}]
+}`
+
+const tomlConfig = `
+default = "deepl-free"
+concurrency = 4
+
+[clients.deepl-free]
+provider = "DeepL"
+api_key = "${SUBLATE_TEST_KEY}"
+timeout = "30s"
+rate_limit = { requests = 5, characters = 100000, characters_per = "1m" }
+
+[clients.deepl-pro]
+provider = "DeepL"
+api_key = "pro-key"
+base_url = "https://deepl-gateway.internal"
+headers = { X-Team = "docs" }
+
+[retry]
+max_attempts = 3
+backoff = "500ms"
+
+[[routes]]
+client = "deepl-pro"
+formats = ["docx", "pdf"]
+
+[[routes]]
+client = "deepl-pro"
+to = ["ja"]
+min_chars = 100
+`
+
+func TestParse(t *testing.T) {
+	// values are expanded once decoded, they can't break the syntax of the file
+	key := "free\"key: #1\nx:fx"
+	t.Setenv("SUBLATE_TEST_KEY", key)
+
+	cases := map[string]struct {
+		data   string
+		format string
+	}{
+		"yaml": {yamlConfig, YAML},
+		"json": {jsonConfig, JSON},
+		"toml": {tomlConfig, TOML},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tc.data), tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			free, pro := cfg.Clients["deepl-free"], cfg.Clients["deepl-pro"]
+			limit := RateLimit{Requests: 5, Characters: 100000, CharactersPer: Duration(time.Minute)}
+			if free.APIKey != key || time.Duration(free.Timeout) != 30*time.Second || free.RateLimit != limit {
+				t.Errorf("unexpected free client %+v", free)
+			}
+			if pro.BaseURL != "https://deepl-gateway.internal" || pro.Headers["X-Team"] != "docs" {
+				t.Errorf("unexpected pro client %+v", pro)
+			}
+			if cfg.Retry.MaxAttempts != 3 || time.Duration(cfg.Retry.Backoff) != 500*time.Millisecond || cfg.Concurrency != 4 {
+				t.Errorf("unexpected settings %+v", cfg)
+			}
+			if len(cfg.Routes) != 2 || cfg.Routes[1].MinChars != 100 {
+				t.Errorf("unexpected routes %+v", cfg.Routes)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg, err := Parse([]byte(yamlConfig), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"SUBLATE_DEEPL_FREE_API_KEY": "env-key:fx",
+		"SUBLATE_DEEPL_PRO_TIMEOUT":  "5s",
+		"SUBLATE_CONCURRENCY":        "8",
+		"SUBLATE_DEFAULT":            "deepl-pro",
+	}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Clients["deepl-free"].APIKey != "env-key:fx" || time.Duration(cfg.Clients["deepl-pro"].Timeout) != 5*time.Second {
+		t.Errorf("client overrides not applied: %+v", cfg.Clients)
+	}
+	if cfg.Concurrency != 8 || cfg.Default != "deepl-pro" {
+		t.Errorf("overrides not applied: %+v", cfg)
+	}
+
+	err = cfg.ApplyEnv(func(k string) string {
+		if k == "SUBLATE_RETRY_BACKOFF" {
+			return "soon"
+		}
+		return ""
+	})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrInvalidConfig || !strings.Contains(err.Error(), "SUBLATE_RETRY_BACKOFF") {
+		t.Errorf("expected ErrInvalidConfig naming the variable, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		data string
+		want []string
+	}{
+		"unknown provider": {"clients: {a: {provider: Babel, api_key: k}}", []string{`unknown provider "Babel"`}},
+		"missing fields":   {"clients: {a: {}}", []string{"missing provider", "missing api_key"}},
+		"no clients":       {"concurrency: 1", []string{"no clients"}},
+		"bad references": {`
+default: b
+clients: {a: {provider: DeepL, api_key: k}}
+routes: [{client: c, formats: [exe]}]`, []string{`default client "b"`, `route 0: client "c"`, `route 0: unknown format "exe"`}},
+		"negative settings": {`
+clients: {a: {provider: DeepL, api_key: k}}
+concurrency: -1
+retry: {max_attempts: -2}`, []string{"negative concurrency", "negative retry"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tc.data), YAML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.Validate()
+			var terr *serr.TranslateError
+			if !errors.As(err, &terr) || terr.Code != serr.ErrInvalidConfig {
+				t.Fatalf("expected ErrInvalidConfig, got %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%q not reported in %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAndBuild(t *testing.T) {
+	t.Setenv("SUBLATE_TEST_KEY", "free-key:fx")
+	path := filepath.Join(t.TempDir(), "sublate.yml")
+	if err := os.WriteFile(path, []byte(yamlConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 2 || clients["deepl-pro"].Name() != provider.DeepL {
+		t.Fatalf("got clients %v", clients)
+	}
+	if _, ok := clients["deepl-free"].(provider.AsyncClient); !ok {
+		t.Error("the rate limited client lost document support")
+	}
+	if instance, err := provider.Instance("deepl-free"); err != nil || instance != clients["deepl-free"] {
+		t.Errorf("clients not registered as instances: %v", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestClientFor(t *testing.T) {
+	cfg, err := Parse([]byte(yamlConfig), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		req   provider.Request
+		chars int
+		want  string
+	}{
+		"document":    {provider.Request{ReqType: format.File, FileName: "a.docx", Binary: []byte("x")}, 0, "deepl-pro"},
+		"long ja":     {provider.Request{ReqType: format.Text, To: lang.Japanese}, 500, "deepl-pro"},
+		"short ja":    {provider.Request{ReqType: format.Text, To: lang.Japanese}, 10, "deepl-free"},
+		"other text":  {provider.Request{ReqType: format.Text, To: lang.German}, 500, "deepl-free"},
+		"other files": {provider.Request{ReqType: format.File, FileName: "a.srt", Binary: []byte("x")}, 0, "deepl-free"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := cfg.ClientFor(tc.req, tc.chars); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	cfg, err := Parse([]byte(yamlConfig), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	free := &namedClient{name: "deepl-free"}
+	pro := &namedClient{name: "deepl-pro"}
+	router := cfg.Router(map[string]provider.Client{"deepl-free": free, "deepl-pro": pro})
+
+	cases := map[string]struct {
+		req  provider.Request
+		want provider.Provider
+	}{
+		"long ja":    {provider.Request{Text: []string{strings.Repeat("a", 500)}, To: lang.Japanese}, "deepl-pro"},
+		"short ja":   {provider.Request{Text: []string{"hello"}, To: lang.Japanese}, "deepl-free"},
+		"other text": {provider.Request{Text: []string{strings.Repeat("a", 500)}, To: lang.German}, "deepl-free"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := router.Translate(context.Background(), tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Provider != tc.want {
+				t.Errorf("got %s, want %s", resp.Provider, tc.want)
+			}
+		})
+	}
+}
+
+// namedClient is a SyncClient echoing the texts back
+type namedClient struct {
+	name provider.Provider
+}
+
+func (c *namedClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	return provider.Response{Text: req.Text}, nil
+}
+
+func (c *namedClient) GetCost(provider.Request) float32  { return 0 }
+func (c *namedClient) GetCharCount(provider.Request) int { return 0 }
+func (c *namedClient) Capabilities() provider.Capabilities {
+	return provider.Capabilities{}
+}
+func (c *namedClient) Name() provider.Provider { return c.name }
+func (c *namedClient) Version() string         { return "test" }
+
+func TestBuildRetry(t *testing.T) {
+	translations := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/translate") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		translations++
+		if translations == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"translations":[{"text":"Hallo"}]}`)
+	}))
+	defer server.Close()
+
+	cfg, err := Parse([]byte(`{
+  "clients": {"retrying": {"provider": "DeepL", "api_key": "key", "base_url": "`+server.URL+`"}},
+  "retry": {"max_attempts": 2, "backoff": "1ms"},
+  "concurrency": 2
+}`), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := []provider.Request{{ReqType: format.Text, Text: []string{"Hello"}, From: lang.English, To: lang.German}}
+	responses, errs := translator.BatchTranslate(context.Background(), reqs, clients["retrying"], cfg.BatchOptions()...)
+	if errs[0] != nil || responses[0].Text[0] != "Hallo" || translations != 2 {
+		t.Errorf("the 503 should be retried, got %+v, %v after %d requests", responses[0], errs[0], translations)
+	}
+}
@@ -1,8 +1,11 @@
 package sublaterr
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 )
 
 type ErrorCode int
@@ -19,6 +22,13 @@ const (
 	ErrNetwork
 	ErrIO
 	ErrSystem
+	ErrUnsupportedOption
+	ErrQuotaExceeded
+	ErrPartialFailure
+	ErrInvalidConfig
+	ErrCircuitOpen // the provider failed too often, calls fail fast until it is probed again
+	ErrBudgetExceeded
+	ErrThrottled // the local rate limiter can't allow the call before the deadline of the caller
 )
 
 type TranslateError struct {
@@ -56,3 +66,35 @@ func New(code ErrorCode, op, provider string, err error) *TranslateError {
 		Err:      err,
 	}
 }
+
+// HTTPError is the cause of ErrHTTP errors returned for unexpected status codes
+type HTTPError struct {
+	StatusCode int
+	TraceID    string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("response code %v , Trace ID: %v", e.StatusCode, e.TraceID)
+}
+
+// Retryable reports whether a request failing with err may succeed later or with another provider:
+// network errors (timeouts included), exceeded quotas, rate limiting, 5xx responses and open circuits.
+// canceled requests are not retryable. a timeout can't be told apart from the deadline of the caller,
+// so callers retrying also check the error of their own context
+func Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var terr *TranslateError
+	if !errors.As(err, &terr) {
+		return false
+	}
+	switch terr.Code {
+	case ErrNetwork, ErrQuotaExceeded, ErrCircuitOpen:
+		return true
+	case ErrHTTP:
+		var herr *HTTPError
+		return errors.As(err, &herr) && (herr.StatusCode >= http.StatusInternalServerError || herr.StatusCode == http.StatusTooManyRequests)
+	}
+	return false
+}
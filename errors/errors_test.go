@@ -1,6 +1,7 @@
 package sublaterr
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -54,3 +55,32 @@ func TestTranslateError_Unwrap(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryable(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"network":       {New(ErrNetwork, "Translate", "deepl", errors.New("connection reset")), true},
+		"quota":         {New(ErrQuotaExceeded, "Translate", "deepl", nil), true},
+		"server error":  {New(ErrHTTP, "Translate", "deepl", &HTTPError{StatusCode: 502}), true},
+		"rate limited":  {New(ErrHTTP, "Translate", "deepl", &HTTPError{StatusCode: 429}), true},
+		"circuit open":  {New(ErrCircuitOpen, "Translate", "deepl", nil), true},
+		"over budget":   {New(ErrBudgetExceeded, "Translate", "deepl", nil), false},
+		"throttled":     {New(ErrThrottled, "Translate", "deepl", fmt.Errorf("limiter: %w", context.DeadlineExceeded)), false},
+		"bad request":   {New(ErrHTTP, "Translate", "deepl", &HTTPError{StatusCode: 400}), false},
+		"wrapped":       {fmt.Errorf("batch: %w", New(ErrQuotaExceeded, "Translate", "deepl", nil)), true},
+		"canceled":      {New(ErrNetwork, "Translate", "deepl", context.Canceled), false},
+		"timeout":       {New(ErrNetwork, "Translate", "deepl", fmt.Errorf("Client.Timeout exceeded: %w", context.DeadlineExceeded)), true},
+		"invalid":       {New(ErrInvalidLanguage, "Translate", "deepl", nil), false},
+		"not sublaterr": {errors.New("boom"), false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Retryable(tc.err); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
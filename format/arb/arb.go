@@ -0,0 +1,152 @@
+// Package arb translates Flutter Application Resource Bundle (ARB) files.
+//
+// Messages are parsed as ICU MessageFormat so only their literal text is sent to the provider,
+// placeholders and plural/select structure are preserved and @key metadata is kept intact.
+package arb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+const localeKey = "@@locale"
+
+type entry struct {
+	key string
+	raw json.RawMessage
+	msg message // nil for metadata and non string values
+}
+
+// Skeleton holds a parsed ARB document with its translatable literals numbered in extraction order
+type Skeleton struct {
+	entries  []entry
+	segments int
+}
+
+// Extract parses an ARB document and returns the literal text fragments of all its messages.
+// leading and trailing whitespace is kept out of the segments and restored on Merge.
+func Extract(data []byte) ([]string, *Skeleton, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("arb: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("arb: document is not a json object")
+	}
+
+	sk := &Skeleton{}
+	var segments []string
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("arb: %w", err)
+		}
+		key := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("arb: value of %q: %w", key, err)
+		}
+		e := entry{key: key, raw: raw}
+
+		var value string
+		if !strings.HasPrefix(key, "@") && json.Unmarshal(raw, &value) == nil {
+			msg, err := parseMessage(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("arb: message %q: %w", key, err)
+			}
+			msg.walk(func(l *literal) {
+				if strings.TrimSpace(l.text) == "" {
+					return
+				}
+				l.index = len(segments)
+				segments = append(segments, strings.TrimSpace(l.text))
+			})
+			e.msg = msg
+		}
+		sk.entries = append(sk.entries, e)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, fmt.Errorf("arb: %w", err)
+	}
+
+	sk.segments = len(segments)
+	return segments, sk, nil
+}
+
+// Merge writes the translated segments back into the skeleton and returns the translated ARB document.
+// plural branches are regenerated for the plural categories of the target language and @@locale is set to it.
+// the skeleton is not modified so it can be merged again for another language.
+func Merge(sk *Skeleton, translated []string, to lang.Language) ([]byte, error) {
+	if len(translated) != sk.segments {
+		return nil, fmt.Errorf("arb: got %d translations for %d segments", len(translated), sk.segments)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+
+	hasLocale := false
+	for i, e := range sk.entries {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		writeString(&buf, e.key)
+		buf.WriteString(": ")
+
+		switch {
+		case e.key == localeKey:
+			hasLocale = true
+			writeString(&buf, Locale(to))
+		case e.msg != nil:
+			msg := e.msg.clone()
+			msg.walk(func(l *literal) {
+				if l.index < 0 {
+					return
+				}
+				lead := l.text[:len(l.text)-len(strings.TrimLeft(l.text, " \t\r\n"))]
+				trail := l.text[len(strings.TrimRight(l.text, " \t\r\n")):]
+				l.text = lead + translated[l.index] + trail
+			})
+			msg.regeneratePlurals(to)
+			writeString(&buf, msg.String())
+		default:
+			if err := json.Indent(&buf, e.raw, "  ", "  "); err != nil {
+				return nil, fmt.Errorf("arb: value of %q: %w", e.key, err)
+			}
+		}
+	}
+
+	if !hasLocale {
+		if len(sk.entries) > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		writeString(&buf, localeKey)
+		buf.WriteString(": ")
+		writeString(&buf, Locale(to))
+	}
+
+	buf.WriteString("\n}\n")
+	return buf.Bytes(), nil
+}
+
+// Locale converts a language to the locale notation used by ARB files. ex: PT-BR -> pt_BR, ZH-HANT -> zh_Hant
+func Locale(l lang.Language) string {
+	return strings.ReplaceAll(l.BCP47(), "-", "_")
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+}
@@ -0,0 +1,165 @@
+package arb
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+func TestParseMessageRoundTrip(t *testing.T) {
+	cases := map[string]struct {
+		input  string
+		output string
+	}{
+		"plain":       {"Hello World", "Hello World"},
+		"placeholder": {"Hello {name}!", "Hello {name}!"},
+		"typed":       {"Total: {amount, number, ::currency/EUR}", "Total: {amount, number, ::currency/EUR}"},
+		"plural":      {"{count, plural, one{# item} other{# items}}", "{count, plural, one{# item} other{# items}}"},
+		"offset":      {"{n,plural,offset:1 =0{nobody} other{# others}}", "{n, plural, offset:1 =0{nobody} other{# others}}"},
+		"select":      {"{gender, select, male{He} female{She} other{They}} liked it", "{gender, select, male{He} female{She} other{They}} liked it"},
+		"apostrophe":  {"Don't panic", "Don't panic"},
+		"quoted":      {"Use '{braces}' and ''quotes''", "Use '{'braces'}' and 'quotes''"},
+		"nested": {
+			"{g, select, male{{n, plural, one{He has # cat} other{He has # cats}}} other{{n, plural, other{They have # cats}}}}",
+			"{g, select, male{{n, plural, one{He has # cat} other{He has # cats}}} other{{n, plural, other{They have # cats}}}}",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			msg, err := parseMessage(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := msg.String(); got != tc.output {
+				t.Errorf("got %q, want %q", got, tc.output)
+			}
+		})
+	}
+}
+
+func TestParseMessageInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unclosed":    "Hello {name",
+		"no branches": "{n, plural, }",
+		"stray brace": "Hello }",
+		"no name":     "Hello {}",
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseMessage(input); err == nil {
+				t.Errorf("expected error for %q", input)
+			}
+		})
+	}
+}
+
+const testARB = `{
+  "@@locale": "en",
+  "greeting": "Hello {name}!",
+  "@greeting": {
+    "description": "Greets the user",
+    "placeholders": {
+      "name": {"type": "String"}
+    }
+  },
+  "items": "{count, plural, =0{No items} one{# item} other{# items}}"
+}`
+
+func TestExtract(t *testing.T) {
+	segments, _, err := Extract([]byte(testARB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello", "!", "No items", "item", "items"}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("got %q, want %q", segments, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	cases := map[string]struct {
+		to         lang.Language
+		translated []string
+		greeting   string
+		items      string
+		locale     string
+	}{
+		"german": {
+			lang.German,
+			[]string{"Hallo", "!", "Keine Artikel", "Artikel", "Artikel"},
+			"Hallo {name}!",
+			"{count, plural, =0{Keine Artikel} one{# Artikel} other{# Artikel}}",
+			"de",
+		},
+		"japanese drops one": {
+			lang.Japanese,
+			[]string{"こんにちは", "！", "アイテムなし", "個", "個"},
+			"こんにちは {name}！",
+			"{count, plural, =0{アイテムなし} other{# 個}}",
+			"ja",
+		},
+		"arabic adds categories": {
+			lang.Arabic,
+			[]string{"مرحبا", "!", "لا عناصر", "عنصر", "عناصر"},
+			"مرحبا {name}!",
+			"{count, plural, =0{لا عناصر} zero{# عناصر} one{# عنصر} two{# عناصر} few{# عناصر} many{# عناصر} other{# عناصر}}",
+			"ar",
+		},
+		"regional locale": {
+			lang.PortugueseBrazil,
+			[]string{"Olá", "!", "Nenhum item", "item", "itens"},
+			"Olá {name}!",
+			"{count, plural, =0{Nenhum item} one{# item} many{# itens} other{# itens}}",
+			"pt_BR",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, sk, err := Extract([]byte(testARB))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Merge(sk, tc.translated, tc.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc map[string]any
+			if err := json.Unmarshal(out, &doc); err != nil {
+				t.Fatalf("invalid json output: %v\n%s", err, out)
+			}
+			if doc["greeting"] != tc.greeting {
+				t.Errorf("greeting: got %q, want %q", doc["greeting"], tc.greeting)
+			}
+			if doc["items"] != tc.items {
+				t.Errorf("items: got %q, want %q", doc["items"], tc.items)
+			}
+			if doc["@@locale"] != tc.locale {
+				t.Errorf("locale: got %q, want %q", doc["@@locale"], tc.locale)
+			}
+			meta := doc["@greeting"].(map[string]any)
+			if meta["description"] != "Greets the user" {
+				t.Errorf("metadata was not preserved: %v", meta)
+			}
+			if strings.Index(string(out), `"greeting"`) > strings.Index(string(out), `"items"`) {
+				t.Errorf("key order was not preserved:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestMergeSegmentMismatch(t *testing.T) {
+	_, sk, err := Extract([]byte(testARB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Merge(sk, []string{"only one"}, lang.German); err == nil {
+		t.Error("expected error for missing translations")
+	}
+}
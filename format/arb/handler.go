@@ -0,0 +1,35 @@
+package arb
+
+import (
+	"fmt"
+
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+func init() {
+	format.Register(Handler{})
+}
+
+// Handler plugs ARB files into the format registry
+type Handler struct{}
+
+func (Handler) MIME() string { return format.ARB.MIME }
+
+func (Handler) Extensions() []string { return format.ARB.Extensions }
+
+func (Handler) Extract(doc []byte) ([]format.Segment, format.Skeleton, error) {
+	segments, sk, err := Extract(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return format.Segments(segments), sk, nil
+}
+
+func (Handler) Merge(sk format.Skeleton, translated []string, to lang.Language) ([]byte, error) {
+	skeleton, ok := sk.(*Skeleton)
+	if !ok {
+		return nil, fmt.Errorf("arb: invalid skeleton %T", sk)
+	}
+	return Merge(skeleton, translated, to)
+}
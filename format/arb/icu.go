@@ -0,0 +1,431 @@
+package arb
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// ICU MessageFormat AST. only literal nodes are ever sent to a provider,
+// everything else is kept as is and printed back around the translations.
+
+type node interface {
+	clone() node
+}
+
+type message []node
+
+// literal text. index is the segment index assigned during extraction, -1 if not translatable
+type literal struct {
+	text  string
+	index int
+}
+
+// simple argument like {name} or {amount, number, ::currency/EUR}
+type argument struct {
+	name  string
+	typ   string
+	style string
+}
+
+// # inside a plural branch
+type pound struct{}
+
+// plural, selectordinal and select arguments
+type choice struct {
+	name     string
+	typ      string
+	offset   string
+	branches []branch
+}
+
+type branch struct {
+	selector string
+	msg      message
+}
+
+func (n *literal) clone() node  { c := *n; return &c }
+func (n *argument) clone() node { c := *n; return &c }
+func (n *pound) clone() node    { return n }
+
+func (n *choice) clone() node {
+	c := *n
+	c.branches = make([]branch, len(n.branches))
+	for i, b := range n.branches {
+		c.branches[i] = branch{selector: b.selector, msg: b.msg.clone()}
+	}
+	return &c
+}
+
+func (m message) clone() message {
+	c := make(message, len(m))
+	for i, n := range m {
+		c[i] = n.clone()
+	}
+	return c
+}
+
+type parser struct {
+	src []rune
+	pos int
+}
+
+func parseMessage(s string) (message, error) {
+	p := &parser{src: []rune(s)}
+	msg, err := p.message(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.src[p.pos], p.pos)
+	}
+	return msg, nil
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() rune { return p.src[p.pos] }
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// message parses until an unmatched '}' or the end of input
+func (p *parser) message(inPlural bool) (message, error) {
+	var msg message
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			msg = append(msg, &literal{text: text.String(), index: -1})
+			text.Reset()
+		}
+	}
+
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case r == '}':
+			flush()
+			return msg, nil
+		case r == '{':
+			flush()
+			n, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			msg = append(msg, n)
+		case r == '#' && inPlural:
+			flush()
+			p.pos++
+			msg = append(msg, &pound{})
+		case r == '\'':
+			p.quoted(&text, inPlural)
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+	flush()
+	return msg, nil
+}
+
+// quoted handles ICU apostrophe quoting: a doubled apostrophe is a literal one and an
+// apostrophe before a syntax char starts a quoted literal up to the next single apostrophe
+func (p *parser) quoted(text *strings.Builder, inPlural bool) {
+	p.pos++
+	if p.eof() {
+		text.WriteRune('\'')
+		return
+	}
+	next := p.peek()
+	if next == '\'' {
+		text.WriteRune('\'')
+		p.pos++
+		return
+	}
+	if next != '{' && next != '}' && next != '|' && !(next == '#' && inPlural) {
+		text.WriteRune('\'')
+		return
+	}
+	for !p.eof() {
+		r := p.peek()
+		p.pos++
+		if r == '\'' {
+			if !p.eof() && p.peek() == '\'' {
+				text.WriteRune('\'')
+				p.pos++
+				continue
+			}
+			return
+		}
+		text.WriteRune(r)
+	}
+}
+
+func (p *parser) identifier() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsSpace(r) || r == ',' || r == '{' || r == '}' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *parser) expect(r rune) error {
+	if p.eof() {
+		return fmt.Errorf("expected %q, got end of message", r)
+	}
+	if p.peek() != r {
+		return fmt.Errorf("expected %q at offset %d, got %q", r, p.pos, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) argument() (node, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	name := p.identifier()
+	if name == "" {
+		return nil, fmt.Errorf("missing argument name at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if !p.eof() && p.peek() == '}' {
+		p.pos++
+		return &argument{name: name}, nil
+	}
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	typ := p.identifier()
+	p.skipSpace()
+
+	switch typ {
+	case "plural", "selectordinal", "select":
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		return p.choice(name, typ)
+	case "":
+		return nil, fmt.Errorf("missing argument type for %q", name)
+	}
+
+	arg := &argument{name: name, typ: typ}
+	if !p.eof() && p.peek() == ',' {
+		p.pos++
+		style, err := p.style()
+		if err != nil {
+			return nil, err
+		}
+		arg.style = style
+	}
+	return arg, p.expect('}')
+}
+
+// style reads the raw argument style up to the closing brace of the argument
+func (p *parser) style() (string, error) {
+	start := p.pos
+	depth := 0
+	for !p.eof() {
+		switch p.peek() {
+		case '\'':
+			p.pos++
+			for !p.eof() && p.peek() != '\'' {
+				p.pos++
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return strings.TrimSpace(string(p.src[start:p.pos])), nil
+			}
+			depth--
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated argument style")
+}
+
+func (p *parser) choice(name, typ string) (node, error) {
+	n := &choice{name: name, typ: typ}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, fmt.Errorf("unterminated %s argument %q", typ, name)
+		}
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+		selector := p.identifier()
+		if selector == "" {
+			return nil, fmt.Errorf("missing selector in %s argument %q", typ, name)
+		}
+		if typ != "select" && strings.HasPrefix(selector, "offset:") {
+			n.offset = strings.TrimPrefix(selector, "offset:")
+			if n.offset == "" {
+				p.skipSpace()
+				n.offset = p.identifier()
+			}
+			continue
+		}
+		p.skipSpace()
+		if err := p.expect('{'); err != nil {
+			return nil, err
+		}
+		msg, err := p.message(typ != "select")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect('}'); err != nil {
+			return nil, err
+		}
+		n.branches = append(n.branches, branch{selector: selector, msg: msg})
+	}
+	if len(n.branches) == 0 {
+		return nil, fmt.Errorf("%s argument %q has no branches", typ, name)
+	}
+	return n, nil
+}
+
+// walk visits every literal of the message in order
+func (m message) walk(fn func(*literal)) {
+	for _, n := range m {
+		switch n := n.(type) {
+		case *literal:
+			fn(n)
+		case *choice:
+			for _, b := range n.branches {
+				b.msg.walk(fn)
+			}
+		}
+	}
+}
+
+// regeneratePlurals rewrites the branches of every plural argument so they match
+// the CLDR cardinal categories of the target language. explicit =N branches are kept,
+// missing categories are copied from the other branch and unused ones are dropped.
+func (m message) regeneratePlurals(to lang.Language) {
+	for _, n := range m {
+		c, ok := n.(*choice)
+		if !ok {
+			continue
+		}
+		for _, b := range c.branches {
+			b.msg.regeneratePlurals(to)
+		}
+		if c.typ != "plural" {
+			continue
+		}
+
+		var other *branch
+		byCategory := map[string]branch{}
+		var explicit []branch
+		for i, b := range c.branches {
+			if strings.HasPrefix(b.selector, "=") {
+				explicit = append(explicit, b)
+				continue
+			}
+			byCategory[b.selector] = b
+			if b.selector == string(lang.Other) {
+				other = &c.branches[i]
+			}
+		}
+		if other == nil {
+			// invalid plural without other branch, leave untouched
+			continue
+		}
+
+		branches := explicit
+		for _, cat := range to.PluralCategories() {
+			if b, ok := byCategory[string(cat)]; ok {
+				branches = append(branches, b)
+				continue
+			}
+			branches = append(branches, branch{selector: string(cat), msg: other.msg.clone()})
+		}
+		c.branches = branches
+	}
+}
+
+func (m message) String() string {
+	var b strings.Builder
+	m.print(&b, false)
+	return b.String()
+}
+
+func (m message) print(b *strings.Builder, inPlural bool) {
+	for _, n := range m {
+		switch n := n.(type) {
+		case *literal:
+			b.WriteString(quote(n.text, inPlural))
+		case *pound:
+			b.WriteByte('#')
+		case *argument:
+			b.WriteByte('{')
+			b.WriteString(n.name)
+			if n.typ != "" {
+				b.WriteString(", ")
+				b.WriteString(n.typ)
+			}
+			if n.style != "" {
+				b.WriteString(", ")
+				b.WriteString(n.style)
+			}
+			b.WriteByte('}')
+		case *choice:
+			b.WriteByte('{')
+			b.WriteString(n.name)
+			b.WriteString(", ")
+			b.WriteString(n.typ)
+			b.WriteByte(',')
+			if n.offset != "" {
+				b.WriteString(" offset:")
+				b.WriteString(n.offset)
+			}
+			for _, br := range n.branches {
+				b.WriteByte(' ')
+				b.WriteString(br.selector)
+				b.WriteByte('{')
+				br.msg.print(b, n.typ != "select")
+				b.WriteByte('}')
+			}
+			b.WriteByte('}')
+		}
+	}
+}
+
+// quote escapes ICU syntax characters in literal text. apostrophes are only doubled
+// where a single one would start a quoted literal, so "it's" stays as is
+func quote(s string, inPlural bool) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == '\'':
+			if i == len(runes)-1 || strings.ContainsRune("'{}|#", runes[i+1]) {
+				b.WriteString("''")
+			} else {
+				b.WriteByte('\'')
+			}
+		case r == '{' || r == '}' || (r == '#' && inPlural):
+			b.WriteByte('\'')
+			b.WriteRune(r)
+			b.WriteByte('\'')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,225 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindText
+	KindDocument
+	KindSubtitle
+	KindLocalization
+	KindEbook
+	KindLocal // only translated by a local handler, never sent to a document endpoint
+)
+
+// Descriptor describes a concrete file type, as opposed to Format which is the transport of a request
+type Descriptor struct {
+	Name       string // short name. ex: docx
+	MIME       string
+	Extensions []string // first one is the canonical extension
+	Kind       Kind
+	Binary     bool
+}
+
+func (d Descriptor) String() string {
+	return d.Name
+}
+
+// Extension returns the canonical extension of the file type, "" if it has none
+func (d Descriptor) Extension() string {
+	if len(d.Extensions) == 0 {
+		return ""
+	}
+	return d.Extensions[0]
+}
+
+var (
+	Unknown = Descriptor{Name: "unknown", MIME: "application/octet-stream", Binary: true}
+	TXT     = Descriptor{Name: "txt", MIME: "text/plain", Extensions: []string{".txt"}, Kind: KindText}
+	HTML    = Descriptor{Name: "html", MIME: "text/html", Extensions: []string{".html", ".htm"}, Kind: KindDocument}
+	XLIFF   = Descriptor{Name: "xliff", MIME: "application/xliff+xml", Extensions: []string{".xlf", ".xliff"}, Kind: KindLocalization}
+	PDF     = Descriptor{Name: "pdf", MIME: "application/pdf", Extensions: []string{".pdf"}, Kind: KindDocument, Binary: true}
+	DOCX    = Descriptor{Name: "docx", MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Extensions: []string{".docx"}, Kind: KindDocument, Binary: true}
+	PPTX    = Descriptor{Name: "pptx", MIME: "application/vnd.openxmlformats-officedocument.presentationml.presentation", Extensions: []string{".pptx"}, Kind: KindDocument, Binary: true}
+	XLSX    = Descriptor{Name: "xlsx", MIME: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extensions: []string{".xlsx"}, Kind: KindDocument, Binary: true}
+	EPUB    = Descriptor{Name: "epub", MIME: "application/epub+zip", Extensions: []string{".epub"}, Kind: KindEbook, Binary: true}
+	SRT     = Descriptor{Name: "srt", MIME: "application/x-subrip", Extensions: []string{".srt"}, Kind: KindSubtitle}
+	VTT     = Descriptor{Name: "vtt", MIME: "text/vtt", Extensions: []string{".vtt"}, Kind: KindSubtitle}
+	PO      = Descriptor{Name: "po", MIME: "text/x-gettext-translation", Extensions: []string{".po", ".pot"}, Kind: KindLocalization}
+	ARB     = Descriptor{Name: "arb", MIME: "application/vnd.flutter.arb+json", Extensions: []string{".arb"}, Kind: KindLocalization}
+)
+
+var (
+	// mu guards descriptors and the handlers of registry.go
+	mu          sync.RWMutex
+	descriptors = []Descriptor{TXT, HTML, XLIFF, PDF, DOCX, PPTX, XLSX, EPUB, SRT, VTT, PO, ARB}
+)
+
+// ByExtension looks up a known file type by extension. ex: ".docx"
+func ByExtension(ext string) (Descriptor, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	ext = strings.ToLower(ext)
+	for _, d := range descriptors {
+		for _, e := range d.Extensions {
+			if e == ext {
+				return d, true
+			}
+		}
+	}
+	return Unknown, false
+}
+
+// ByMIME looks up a known file type by media type
+func ByMIME(mime string) (Descriptor, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return byMIME(mime)
+}
+
+func byMIME(mime string) (Descriptor, bool) {
+	for _, d := range descriptors {
+		if d.MIME == mime {
+			return d, true
+		}
+	}
+	return Unknown, false
+}
+
+var (
+	srtTiming = regexp.MustCompile(`(?m)^\d{1,2}:\d{2}:\d{2},\d{3}\s+-->\s+\d{1,2}:\d{2}:\d{2},\d{3}`)
+	poMsgid   = regexp.MustCompile(`(?m)^msgid\s+"`)
+	poMsgstr  = regexp.MustCompile(`(?m)^msgstr(\[\d+\])?\s+"`)
+)
+
+// sniffed is how much of a text file is inspected
+const sniffed = 4096
+
+// Detect returns the file type of a registered extension of filename, the content is only sniffed (magic bytes, then text)
+// when the extension is missing or unknown. returns Unknown if nothing matches
+func Detect(filename string, data []byte) Descriptor {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if d, ok := ByExtension(ext); ok {
+		return d
+	}
+	if d, ok := detectMagic(data); ok {
+		return d
+	}
+	if d, ok := detectText(data, ext); ok {
+		return d
+	}
+	if len(data) > 0 && utf8.Valid(head(data)) {
+		return TXT
+	}
+	return Unknown
+}
+
+func head(data []byte) []byte {
+	if len(data) <= sniffed {
+		return data
+	}
+	// don't cut a multi byte rune in half
+	end := sniffed
+	for end > 0 && !utf8.RuneStart(data[end]) {
+		end--
+	}
+	return data[:end]
+}
+
+func detectMagic(data []byte) (Descriptor, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return PDF, true
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return detectZip(data)
+	}
+	return Unknown, false
+}
+
+// detectZip tells zip based containers apart from their entries
+func detectZip(data []byte) (Descriptor, bool) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Unknown, false
+	}
+	for _, f := range r.File {
+		switch {
+		case f.Name == "mimetype":
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			mime, _ := io.ReadAll(io.LimitReader(rc, 64))
+			rc.Close()
+			if strings.TrimSpace(string(mime)) == EPUB.MIME {
+				return EPUB, true
+			}
+		case strings.HasPrefix(f.Name, "word/"):
+			return DOCX, true
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return PPTX, true
+		case strings.HasPrefix(f.Name, "xl/"):
+			return XLSX, true
+		}
+	}
+	return Unknown, false
+}
+
+func detectText(data []byte, ext string) (Descriptor, bool) {
+	text := head(data)
+	text = bytes.TrimPrefix(text, []byte("\xef\xbb\xbf")) // utf-8 BOM
+	if !utf8.Valid(text) {
+		return Unknown, false
+	}
+	trimmed := bytes.TrimSpace(text)
+	lower := bytes.ToLower(trimmed)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("WEBVTT")):
+		return VTT, true
+	case srtTiming.Match(text):
+		return SRT, true
+	case poMsgid.Match(text) && poMsgstr.Match(text):
+		return PO, true
+	case bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.HasPrefix(lower, []byte("<html")):
+		return HTML, true
+	case bytes.Contains(lower, []byte("<xliff")):
+		return XLIFF, true
+	// any json file may look like ARB, so only files without an extension are sniffed for it
+	case ext == "" && bytes.HasPrefix(trimmed, []byte("{")) && isARB(data):
+		return ARB, true
+	}
+	return Unknown, false
+}
+
+// isARB reports whether data is a json object with a @@locale key or a message and its @metadata.
+// a lone @ key (ex: the @context of JSON-LD) isn't enough
+func isARB(data []byte) bool {
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(data, &doc) != nil {
+		return false
+	}
+	if _, ok := doc["@@locale"]; ok {
+		return true
+	}
+	for key := range doc {
+		if message, ok := strings.CutPrefix(key, "@"); ok && message != "" && !strings.HasPrefix(message, "@") {
+			if _, ok := doc[message]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
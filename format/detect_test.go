@@ -0,0 +1,89 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func zipWith(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	cases := map[string]struct {
+		filename string
+		data     []byte
+		want     Descriptor
+	}{
+		"pdf magic":          {"upload", []byte("%PDF-1.7\n..."), PDF},
+		"docx":               {"", zipWith(t, map[string]string{"word/document.xml": "<w:document/>"}), DOCX},
+		"pptx":               {"", zipWith(t, map[string]string{"ppt/presentation.xml": "<p/>"}), PPTX},
+		"xlsx":               {"", zipWith(t, map[string]string{"xl/workbook.xml": "<w/>"}), XLSX},
+		"epub":               {"book.zip", zipWith(t, map[string]string{"mimetype": "application/epub+zip"}), EPUB},
+		"unknown zip by ext": {"archive.docx", zipWith(t, map[string]string{"a.txt": "a"}), DOCX},
+		"srt":                {"subs", []byte("1\n00:00:01,000 --> 00:00:02,500\nHello\n"), SRT},
+		"srt as txt":         {"subs.txt", []byte("1\n00:00:01,000 --> 00:00:02,500\nHello\n"), TXT},
+		"pdf as docx":        {"report.docx", []byte("%PDF-1.7\n..."), DOCX},
+		"epub as pdf":        {"book.pdf", zipWith(t, map[string]string{"mimetype": "application/epub+zip"}), PDF},
+		"html as xliff":      {"strings.xlf", []byte("<html><body>Hi</body></html>"), XLIFF},
+		"po as srt":          {"subs.srt", []byte("msgid \"Hello\"\nmsgstr \"Hallo\"\n"), SRT},
+		"vtt as html":        {"page.HTML", []byte("WEBVTT\n"), HTML},
+		"unregistered ext":   {"upload.bin", []byte("%PDF-1.7\n..."), PDF},
+		"vtt":                {"", []byte("\xef\xbb\xbfWEBVTT\n\n00:01.000 --> 00:02.000\nHi\n"), VTT},
+		"po":                 {"", []byte("msgid \"\"\nmsgstr \"\"\n\"Language: de\\n\"\n\nmsgid \"Hello\"\nmsgstr \"Hallo\"\n"), PO},
+		"arb":                {"", []byte(`{"@@locale": "en", "hello": "Hello"}`), ARB},
+		"plain json":         {"data.json", []byte(`{"hello": "Hello"}`), TXT},
+		"arb metadata":       {"", []byte(`{"hello": "Hello", "@hello": {}}`), ARB},
+		"json-ld":            {"", []byte(`{"@context": "https://schema.org", "name": "Hello"}`), TXT},
+		"arb as json":        {"data.json", []byte(`{"@@locale": "en", "hello": "Hello"}`), TXT},
+		"arb extension":      {"app_en.arb", []byte(`{"hello": "Hello"}`), ARB},
+		"html":               {"", []byte("<!DOCTYPE html><html><body>Hi</body></html>"), HTML},
+		"xliff":              {"", []byte(`<?xml version="1.0"?><xliff version="1.2"></xliff>`), XLIFF},
+		"extension only":     {"notes.TXT", []byte{0xff, 0xfe, 0x00}, TXT},
+		"plain text":         {"", []byte("just some text"), TXT},
+		"binary":             {"blob", []byte{0x00, 0xff, 0xfe, 0x80}, Unknown},
+		"empty":              {"", nil, Unknown},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Detect(tc.filename, tc.data); got.Name != tc.want.Name {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestByExtension(t *testing.T) {
+	cases := map[string]struct {
+		ext  string
+		want Descriptor
+		ok   bool
+	}{
+		"lower":   {".srt", SRT, true},
+		"upper":   {".HTM", HTML, true},
+		"alias":   {".pot", PO, true},
+		"unknown": {".exe", Unknown, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, ok := ByExtension(tc.ext)
+			if got.Name != tc.want.Name || ok != tc.ok {
+				t.Errorf("got (%s, %v), want (%s, %v)", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,465 @@
+// Package epub translates EPUB e-books locally.
+//
+// The OPF package document is read from the OCF container, every XHTML content document of the
+// spine is translated block by block with its inline markup sent as xml tags, the navigation titles
+// (nav.xhtml, toc.ncx) and dc:title are translated and the language metadata is set to the target.
+// segments are xml fragments, so they must be translated with xml tag handling.
+package epub
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/o0n1x/sublate-go/format/internal/archive"
+	"github.com/o0n1x/sublate-go/format/internal/xmltext"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+const (
+	containerPath = "META-INF/container.xml"
+	xhtmlType     = "application/xhtml+xml"
+	ncxType       = "application/x-dtbncx+xml"
+)
+
+// slot is a translatable range of a document, its text is an escaped xml fragment.
+// lead and trail keep the whitespace around its trimmed text
+type slot struct {
+	start   int
+	end     int
+	text    string
+	lead    string
+	trail   string
+	segment int
+}
+
+type document struct {
+	file int
+	// text nodes to translate
+	slots []slot
+	// start tags whose language attributes are set to the target
+	langTags []xmltext.Token
+	// content ranges of dc:language elements
+	langText [][2]int
+}
+
+// Skeleton holds the unzipped book and the location of every segment
+type Skeleton struct {
+	files    []archive.File
+	docs     []document
+	segments int
+}
+
+type manifestItem struct {
+	href       string
+	mediaType  string
+	properties string
+}
+
+// Extract opens the book and returns the text of its title, navigation and content documents in reading order
+func Extract(data []byte) ([]string, *Skeleton, error) {
+	files, err := archive.Read(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("epub: %w", err)
+	}
+	index := map[string]int{}
+	for i, f := range files {
+		index[f.Header.Name] = i
+	}
+
+	container, ok := index[containerPath]
+	if !ok {
+		return nil, nil, fmt.Errorf("epub: %s not found", containerPath)
+	}
+	opfPath, err := rootfile(files[container].Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("epub: %s: %w", containerPath, err)
+	}
+	opf, ok := index[opfPath]
+	if !ok {
+		return nil, nil, fmt.Errorf("epub: package document %s not found", opfPath)
+	}
+
+	sk := &Skeleton{files: files}
+	var segments []string
+
+	add := func(file int, extract func([]byte) (document, error)) error {
+		doc, err := extract(files[file].Data)
+		if err != nil {
+			return fmt.Errorf("epub: %s: %w", files[file].Header.Name, err)
+		}
+		doc.file = file
+		for i := range doc.slots {
+			doc.slots[i].segment = len(segments)
+			segments = append(segments, doc.slots[i].text)
+		}
+		sk.docs = append(sk.docs, doc)
+		return nil
+	}
+
+	if err := add(opf, extractPackage); err != nil {
+		return nil, nil, err
+	}
+
+	manifest, spine, err := readPackage(files[opf].Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("epub: %s: %w", opfPath, err)
+	}
+
+	resolve := func(href string) (int, bool) {
+		p, err := url.PathUnescape(href)
+		if err != nil {
+			p = href
+		}
+		i, ok := index[path.Join(path.Dir(opfPath), p)]
+		return i, ok
+	}
+
+	// navigation documents first, then the spine
+	var order []string
+	for id, item := range manifest {
+		if item.mediaType == ncxType || strings.Contains(" "+item.properties+" ", " nav ") {
+			order = append(order, id)
+		}
+	}
+	slices.Sort(order)
+	order = append(order, spine...)
+
+	seen := map[int]bool{}
+	for _, id := range order {
+		item, ok := manifest[id]
+		if !ok {
+			continue
+		}
+		file, ok := resolve(item.href)
+		if !ok || seen[file] {
+			continue
+		}
+		seen[file] = true
+
+		switch item.mediaType {
+		case xhtmlType:
+			err = add(file, extractXHTML)
+		case ncxType:
+			err = add(file, extractNCX)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sk.segments = len(segments)
+	return segments, sk, nil
+}
+
+func rootfile(data []byte) (string, error) {
+	tokens, err := xmltext.Tokenize(data)
+	if err != nil {
+		return "", err
+	}
+	for _, tok := range tokens {
+		if tok.Kind == xmltext.StartElement && tok.Name.Local == "rootfile" {
+			if p, ok := tok.AttrValue("", "full-path"); ok {
+				return p, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no rootfile")
+}
+
+func readPackage(data []byte) (map[string]manifestItem, []string, error) {
+	tokens, err := xmltext.Tokenize(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := map[string]manifestItem{}
+	var spine []string
+	for _, tok := range tokens {
+		if tok.Kind != xmltext.StartElement {
+			continue
+		}
+		switch tok.Name.Local {
+		case "item":
+			id, _ := tok.AttrValue("", "id")
+			href, _ := tok.AttrValue("", "href")
+			mediaType, _ := tok.AttrValue("", "media-type")
+			properties, _ := tok.AttrValue("", "properties")
+			manifest[id] = manifestItem{href: href, mediaType: mediaType, properties: properties}
+		case "itemref":
+			idref, _ := tok.AttrValue("", "idref")
+			spine = append(spine, idref)
+		}
+	}
+	return manifest, spine, nil
+}
+
+// walk collects the non blank text nodes for which translatable returns true, given the local names of the open elements
+func walk(data []byte, translatable func(stack []string) bool, visit func(tok xmltext.Token, stack []string)) ([]slot, error) {
+	tokens, err := xmltext.Tokenize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []slot
+	var stack []string
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case xmltext.StartElement:
+			stack = append(stack, tok.Name.Local)
+			if visit != nil {
+				visit(tok, stack)
+			}
+		case xmltext.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xmltext.CharData:
+			if strings.TrimSpace(tok.Text) != "" && translatable(stack) {
+				slots = append(slots, newSlot(tok.Start, tok.End, xmltext.Escape(tok.Text)))
+			}
+		}
+	}
+	return slots, nil
+}
+
+func newSlot(start, end int, text string) slot {
+	trimmed := strings.TrimLeft(text, " \t\r\n")
+	lead := text[:len(text)-len(trimmed)]
+	trimmed = strings.TrimRight(trimmed, " \t\r\n")
+	trail := text[len(lead)+len(trimmed):]
+	return slot{start: start, end: end, text: trimmed, lead: lead, trail: trail}
+}
+
+// skippedElements are kept as is, inside a block they are sent as ignored tags
+var skippedElements = []string{"script", "style", "code", "kbd", "samp", "var", "math", "svg"}
+
+// inlineElements don't split blocks, they are sent as tags within the text of their block
+var inlineElements = []string{
+	"a", "abbr", "b", "bdi", "bdo", "br", "cite", "del", "dfn", "em", "i", "img", "ins", "mark",
+	"q", "rp", "rt", "ruby", "s", "small", "span", "strong", "sub", "sup", "time", "u", "wbr",
+}
+
+// runToken is a token of a block. skipped tokens are inside skipped elements
+type runToken struct {
+	tok     xmltext.Token
+	skipped bool
+}
+
+// blocks collects the content of every block element as a single slot, inline elements and the
+// text around them included. translatable gets the local names of the open elements
+func blocks(data []byte, translatable func(stack []string) bool, visit func(tok xmltext.Token, stack []string)) ([]slot, error) {
+	tokens, err := xmltext.Tokenize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []slot
+	var stack []string
+	var run []runToken
+	hasText := false
+	skipped := 0
+
+	flush := func() {
+		if hasText {
+			slots = append(slots, runSlots(data, run)...)
+		}
+		run = run[:0]
+		hasText = false
+	}
+
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case xmltext.StartElement:
+			stack = append(stack, tok.Name.Local)
+			if visit != nil {
+				visit(tok, stack)
+			}
+			inline := skipped > 0 || slices.Contains(inlineElements, tok.Name.Local) || slices.Contains(skippedElements, tok.Name.Local)
+			if !inline || !translatable(stack) {
+				flush()
+				continue
+			}
+			if skipped > 0 || slices.Contains(skippedElements, tok.Name.Local) {
+				skipped++
+			}
+			run = append(run, runToken{tok, skipped > 0})
+		case xmltext.EndElement:
+			switch {
+			case skipped > 0:
+				run = append(run, runToken{tok, true})
+				skipped--
+			case slices.Contains(inlineElements, tok.Name.Local) && translatable(stack):
+				run = append(run, runToken{tok, false})
+			default:
+				flush()
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xmltext.CharData:
+			if !translatable(stack) {
+				continue
+			}
+			run = append(run, runToken{tok, skipped > 0})
+			if skipped == 0 && strings.TrimSpace(tok.Text) != "" {
+				hasText = true
+			}
+		default:
+			// comments and processing instructions are kept within a block
+			if len(run) > 0 {
+				run = append(run, runToken{tok, skipped > 0})
+			}
+		}
+	}
+	flush()
+	return slots, nil
+}
+
+// runSlots turns the tokens of a block into a slot. blocks whose inline elements aren't
+// balanced (an inline element around a block) fall back to a slot per text node
+func runSlots(data []byte, run []runToken) []slot {
+	depth := 0
+	for _, rt := range run {
+		switch rt.tok.Kind {
+		case xmltext.StartElement:
+			depth++
+		case xmltext.EndElement:
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+
+	if depth != 0 {
+		var slots []slot
+		for _, rt := range run {
+			if rt.tok.Kind == xmltext.CharData && !rt.skipped && strings.TrimSpace(rt.tok.Text) != "" {
+				slots = append(slots, newSlot(rt.tok.Start, rt.tok.End, xmltext.Escape(rt.tok.Text)))
+			}
+		}
+		return slots
+	}
+
+	var b strings.Builder
+	for _, rt := range run {
+		if rt.tok.Kind == xmltext.CharData {
+			// entities like &nbsp; are written as characters so the fragment is plain xml
+			b.WriteString(xmltext.Escape(rt.tok.Text))
+		} else {
+			b.Write(data[rt.tok.Start:rt.tok.End])
+		}
+	}
+	return []slot{newSlot(run[0].tok.Start, run[len(run)-1].tok.End, b.String())}
+}
+
+func extractXHTML(data []byte) (document, error) {
+	var doc document
+	slots, err := blocks(data, func(stack []string) bool {
+		return len(stack) >= 2 && (stack[1] == "body" || slices.Contains(stack, "title"))
+	}, func(tok xmltext.Token, stack []string) {
+		if len(stack) == 1 && tok.Name.Local == "html" {
+			doc.langTags = append(doc.langTags, tok)
+		}
+	})
+	doc.slots = slots
+	return doc, err
+}
+
+func extractNCX(data []byte) (document, error) {
+	var doc document
+	slots, err := walk(data, func(stack []string) bool {
+		return len(stack) >= 2 && stack[len(stack)-1] == "text" && (stack[len(stack)-2] == "navLabel" || stack[len(stack)-2] == "docTitle")
+	}, func(tok xmltext.Token, stack []string) {
+		if _, ok := tok.AttrValue("xml", "lang"); ok && len(stack) == 1 {
+			doc.langTags = append(doc.langTags, tok)
+		}
+	})
+	doc.slots = slots
+	return doc, err
+}
+
+func extractPackage(data []byte) (document, error) {
+	var doc document
+	slots, err := walk(data, func(stack []string) bool {
+		return len(stack) > 0 && stack[len(stack)-1] == "title" && slices.Contains(stack, "metadata")
+	}, func(tok xmltext.Token, stack []string) {
+		if _, ok := tok.AttrValue("xml", "lang"); ok && len(stack) == 1 {
+			doc.langTags = append(doc.langTags, tok)
+		}
+	})
+	if err != nil {
+		return doc, err
+	}
+	doc.slots = slots
+
+	// dc:language content ranges
+	tokens, _ := xmltext.Tokenize(data)
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Kind == xmltext.StartElement && tokens[i].Name.Space == "dc" && tokens[i].Name.Local == "language" {
+			end := tokens[i+1].Start
+			if tokens[i+1].Kind == xmltext.CharData {
+				end = tokens[i+1].End
+			}
+			doc.langText = append(doc.langText, [2]int{tokens[i].End, end})
+		}
+	}
+	return doc, nil
+}
+
+// Merge writes the translations back into the book, sets its language metadata to the target and rezips it
+func Merge(sk *Skeleton, translated []string, to lang.Language) ([]byte, error) {
+	if len(translated) != sk.segments {
+		return nil, fmt.Errorf("epub: got %d translations for %d segments", len(translated), sk.segments)
+	}
+
+	tag := to.BCP47()
+	files := slices.Clone(sk.files)
+	for _, doc := range sk.docs {
+		data := files[doc.file].Data
+		var edits []xmltext.Edit
+		for _, s := range doc.slots {
+			text := translated[s.segment]
+			// a translation with broken markup would corrupt the whole document
+			if _, err := xmltext.Tokenize([]byte("<s>" + text + "</s>")); err != nil {
+				return nil, fmt.Errorf("epub: %s: invalid translation of segment %d: %w", files[doc.file].Header.Name, s.segment, err)
+			}
+			edits = append(edits, xmltext.Edit{Start: s.start, End: s.end, Text: s.lead + text + s.trail})
+		}
+		for _, tok := range doc.langTags {
+			start := string(data[tok.Start:tok.End])
+			updated := xmltext.SetAttr(start, "xml:lang", tag)
+			if _, ok := tok.AttrValue("", "lang"); ok || tok.Name.Local == "html" {
+				updated = xmltext.SetAttr(updated, "lang", tag)
+			}
+			edits = append(edits, xmltext.Edit{Start: tok.Start, End: tok.End, Text: updated})
+		}
+		for _, r := range doc.langText {
+			edits = append(edits, xmltext.Edit{Start: r[0], End: r[1], Text: tag})
+		}
+		files[doc.file].Data = xmltext.Apply(data, edits)
+	}
+
+	out, err := archive.Write(files)
+	if err != nil {
+		return nil, fmt.Errorf("epub: %w", err)
+	}
+	return out, nil
+}
+
+// CharCount returns the number of characters in the segments of the book, inline tags included since they are sent along with the text
+func CharCount(data []byte) (int, error) {
+	segments, _, err := Extract(data)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, s := range segments {
+		total += utf8.RuneCountInString(s)
+	}
+	return total, nil
+}
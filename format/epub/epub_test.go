@@ -0,0 +1,210 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+var book = []struct {
+	name    string
+	content string
+}{
+	{"mimetype", "application/epub+zip"},
+	{"META-INF/container.xml", `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`},
+	{"OEBPS/content.opf", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" xml:lang="en">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>The Lighthouse</dc:title>
+    <dc:language>en</dc:language>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="ch1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine toc="ncx"><itemref idref="ch1"/></spine>
+</package>`},
+	{"OEBPS/nav.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>Contents</title></head>
+<body><nav epub:type="toc"><ol><li><a href="text/chapter%201.xhtml">Chapter One</a></li></ol></nav></body></html>`},
+	{"OEBPS/toc.ncx", `<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1" xml:lang="en">
+<docTitle><text>The Lighthouse</text></docTitle>
+<navMap><navPoint id="p1"><navLabel><text>Chapter One</text></navLabel><content src="text/chapter%201.xhtml"/></navPoint></navMap></ncx>`},
+	{"OEBPS/text/chapter 1.xhtml", `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en"><head><title>Chapter One</title><style>p { margin: 0 }</style></head>
+<body><h1>Chapter One</h1><p>The <em>old</em> keeper&nbsp;waited.</p><p><code>x = 1</code></p>
+<div>Run <code>x &lt; 1</code> <!-- note --> twice<p>Nested &amp; block</p></div><div><a href="#n">Broken<br/><p>inside</p></a></div></body></html>`},
+	{"OEBPS/style.css", "p { margin: 0 }"},
+}
+
+func buildBook(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for i, f := range book {
+		method := zip.Deflate
+		if i == 0 {
+			method = zip.Store
+		}
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	segments, _, err := Extract(buildBook(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"The Lighthouse",                                               // dc:title
+		"Contents", `<a href="text/chapter%201.xhtml">Chapter One</a>`, // nav.xhtml
+		"The Lighthouse", "Chapter One", // toc.ncx
+		"Chapter One", "Chapter One", "The <em>old</em> keeper\u00a0waited.", // chapter
+		"Run <code>x &lt; 1</code> <!-- note --> twice", "Nested &amp; block",
+		"Broken", "inside", // an inline element around a block
+	}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("got %q, want %q", segments, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	segments, sk, err := Extract(buildBook(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated := make([]string, len(segments))
+	for i, s := range segments {
+		translated[i] = upper(s)
+	}
+
+	out, err := Merge(sk, translated, lang.PortugueseBrazil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.File[0].Name != "mimetype" || r.File[0].Method != zip.Store {
+		t.Errorf("mimetype must be the first stored entry, got %s (method %d)", r.File[0].Name, r.File[0].Method)
+	}
+
+	contents := map[string]string{}
+	for _, f := range r.File {
+		rc, _ := f.Open()
+		var b bytes.Buffer
+		b.ReadFrom(rc)
+		rc.Close()
+		contents[f.Name] = b.String()
+	}
+
+	cases := map[string]struct {
+		file     string
+		contains []string
+	}{
+		"package": {"OEBPS/content.opf", []string{
+			`<dc:title>THE LIGHTHOUSE</dc:title>`,
+			`<dc:language>pt-BR</dc:language>`,
+			`xml:lang="pt-BR"`,
+		}},
+		"ncx": {"OEBPS/toc.ncx", []string{`<text>CHAPTER ONE</text>`, `xml:lang="pt-BR"`}},
+		"nav": {"OEBPS/nav.xhtml", []string{`<a href="text/chapter%201.xhtml">CHAPTER ONE</a>`, `xml:lang="pt-BR" lang="pt-BR"`}},
+		"chapter": {"OEBPS/text/chapter 1.xhtml", []string{
+			`<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="pt-BR" lang="pt-BR">`,
+			`<p>THE <em>OLD</em> KEEPER` + "\u00a0" + `WAITED.</p>`,
+			`<code>x = 1</code>`,
+			`<div>RUN <code>x &lt; 1</code> <!-- note --> TWICE<p>NESTED &amp; BLOCK</p></div>`,
+			`<a href="#n">BROKEN<br/><p>INSIDE</p></a>`,
+			`<style>p { margin: 0 }</style>`,
+		}},
+		"untouched": {"OEBPS/style.css", []string{"p { margin: 0 }"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, want := range tc.contains {
+				if !strings.Contains(contents[tc.file], want) {
+					t.Errorf("%s does not contain %q:\n%s", tc.file, want, contents[tc.file])
+				}
+			}
+		})
+	}
+}
+
+// upper upper cases the text of an xml fragment like a provider with xml tag handling would,
+// leaving tags, entities and the content of code elements alone
+func upper(s string) string {
+	var b strings.Builder
+	inTag, inEntity, inCode := false, false, false
+	for i, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+			inCode = strings.HasPrefix(s[i:], "<code") || (inCode && !strings.HasPrefix(s[i:], "</code"))
+		case r == '>':
+			inTag = false
+		case r == '&':
+			inEntity = true
+		case r == ';':
+			inEntity = false
+		}
+		if inTag || inEntity || inCode {
+			b.WriteRune(r)
+		} else {
+			b.WriteString(strings.ToUpper(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func TestMergeInvalidTranslation(t *testing.T) {
+	segments, sk, err := Extract(buildBook(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated := slices.Clone(segments)
+	translated[len(translated)-1] = "<a>unclosed"
+	if _, err := Merge(sk, translated, lang.German); err == nil {
+		t.Error("expected broken markup to be rejected")
+	}
+}
+
+func TestExtractInvalid(t *testing.T) {
+	cases := map[string][]byte{
+		"not a zip": []byte("not a zip"),
+		"no container": func() []byte {
+			var b bytes.Buffer
+			w := zip.NewWriter(&b)
+			w.Create("mimetype")
+			w.Close()
+			return b.Bytes()
+		}(),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := Extract(data); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
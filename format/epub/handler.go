@@ -0,0 +1,40 @@
+package epub
+
+import (
+	"fmt"
+	"slices"
+
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+func init() {
+	format.Register(Handler{})
+}
+
+// Handler plugs EPUB books into the format registry
+type Handler struct{}
+
+func (Handler) MIME() string { return format.EPUB.MIME }
+
+func (Handler) Extensions() []string { return format.EPUB.Extensions }
+
+func (Handler) IgnoreTags() []string { return slices.Clone(skippedElements) }
+
+func (Handler) Extract(doc []byte) ([]format.Segment, format.Skeleton, error) {
+	segments, sk, err := Extract(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return format.Segments(segments), sk, nil
+}
+
+func (Handler) CharCount(doc []byte) (int, error) { return CharCount(doc) }
+
+func (Handler) Merge(sk format.Skeleton, translated []string, to lang.Language) ([]byte, error) {
+	skeleton, ok := sk.(*Skeleton)
+	if !ok {
+		return nil, fmt.Errorf("epub: invalid skeleton %T", sk)
+	}
+	return Merge(skeleton, translated, to)
+}
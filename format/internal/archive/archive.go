@@ -0,0 +1,63 @@
+// Package archive reads and rewrites zip based document containers (OOXML, EPUB)
+// keeping the order and compression of their entries.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+type File struct {
+	Header zip.FileHeader
+	Data   []byte
+}
+
+func Read(data []byte) ([]File, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(r.File))
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		files = append(files, File{Header: f.FileHeader, Data: content})
+	}
+	return files, nil
+}
+
+func Write(files []File) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		header := f.Header
+		// sizes and checksums are recomputed by the writer
+		header.CompressedSize64, header.UncompressedSize64, header.CRC32 = 0, 0, 0
+		header.CompressedSize, header.UncompressedSize = 0, 0
+		header.Flags = 0
+
+		fw, err := w.CreateHeader(&header)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Header.Name, err)
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Header.Name, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
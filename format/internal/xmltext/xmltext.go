@@ -0,0 +1,147 @@
+// Package xmltext tokenizes XML documents with byte offsets so text can be
+// replaced in place without re-encoding (and mangling) the rest of the markup.
+package xmltext
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Kind int
+
+const (
+	StartElement Kind = iota
+	EndElement
+	CharData
+	Other // comments, processing instructions and directives
+)
+
+// Token is a raw xml token and the byte range [Start, End) it spans in the document
+type Token struct {
+	Kind  Kind
+	Name  xml.Name // Space holds the raw prefix
+	Attr  []xml.Attr
+	Text  string // unescaped character data
+	Start int
+	End   int
+}
+
+// Tokenize splits an xml document into tokens. html entities like &nbsp; are accepted for xhtml documents
+func Tokenize(data []byte) ([]Token, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Entity = xml.HTMLEntity
+
+	var tokens []Token
+	var open []xml.Name // RawToken doesn't check nesting, so it is done here
+	for {
+		start := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			if len(open) > 0 {
+				return nil, fmt.Errorf("unexpected EOF: element <%s> is not closed", qualified(open[len(open)-1]))
+			}
+			return tokens, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := int(d.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			tokens = append(tokens, Token{Kind: StartElement, Name: t.Name, Attr: t.Attr, Start: start, End: end})
+			open = append(open, t.Name)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, fmt.Errorf("unexpected end element </%s> at offset %d", qualified(t.Name), start)
+			}
+			open = open[:len(open)-1]
+			// the end of a self closing element has no width, place it right after its start
+			if start == end {
+				start, end = tokens[len(tokens)-1].End, tokens[len(tokens)-1].End
+			}
+			tokens = append(tokens, Token{Kind: EndElement, Name: t.Name, Start: start, End: end})
+		case xml.CharData:
+			tokens = append(tokens, Token{Kind: CharData, Text: string(t), Start: start, End: end})
+		default:
+			tokens = append(tokens, Token{Kind: Other, Start: start, End: end})
+		}
+	}
+}
+
+func qualified(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// AttrValue returns the value of the attribute with the given raw prefix and local name
+func (t Token) AttrValue(prefix, local string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Name.Space == prefix && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Edit replaces the bytes [Start, End) of a document with Text, which must already be escaped
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Apply applies non overlapping edits to the document
+func Apply(data []byte, edits []Edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	last := 0
+	for _, e := range edits {
+		buf.Write(data[last:e.Start])
+		buf.WriteString(e.Text)
+		last = e.End
+	}
+	buf.Write(data[last:])
+	return buf.Bytes()
+}
+
+// attrPattern matches an attribute of a raw start tag, its name and its quoted value
+var attrPattern = regexp.MustCompile(`\s([^\s=/>]+)\s*=\s*("[^"]*"|'[^']*')`)
+
+// SetAttr sets the attribute name (with its raw prefix, ex: xml:lang) of a raw start tag, adding it if missing
+func SetAttr(tag string, name, value string) string {
+	for _, loc := range attrPattern.FindAllStringSubmatchIndex(tag, -1) {
+		if tag[loc[2]:loc[3]] == name {
+			return tag[:loc[4]] + `"` + EscapeAttr(value) + `"` + tag[loc[5]:]
+		}
+	}
+	end := len(tag) - 1
+	if strings.HasSuffix(tag, "/>") {
+		end--
+	}
+	return tag[:end] + " " + name + `="` + EscapeAttr(value) + `"` + tag[end:]
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escape escapes character data. quotes are left alone since they are only special in attributes
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// EscapeAttr escapes an attribute value for use inside double quotes
+func EscapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
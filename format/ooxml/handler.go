@@ -0,0 +1,42 @@
+package ooxml
+
+import (
+	"fmt"
+
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+func init() {
+	format.Register(Handler{format.DOCX})
+	format.Register(Handler{format.PPTX})
+	format.Register(Handler{format.XLSX})
+}
+
+// Handler plugs one of the Office Open XML file types into the format registry
+type Handler struct {
+	descriptor format.Descriptor
+}
+
+func (h Handler) MIME() string { return h.descriptor.MIME }
+
+func (h Handler) Extensions() []string { return h.descriptor.Extensions }
+
+func (Handler) Extract(doc []byte) ([]format.Segment, format.Skeleton, error) {
+	segments, sk, err := Extract(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return format.Segments(segments), sk, nil
+}
+
+func (Handler) CharCount(doc []byte) (int, error) { return CharCount(doc) }
+
+// Merge writes the translations back. the target language is not recorded in the document
+func (Handler) Merge(sk format.Skeleton, translated []string, _ lang.Language) ([]byte, error) {
+	skeleton, ok := sk.(*Skeleton)
+	if !ok {
+		return nil, fmt.Errorf("ooxml: invalid skeleton %T", sk)
+	}
+	return Merge(skeleton, translated)
+}
@@ -0,0 +1,366 @@
+// Package ooxml translates Office Open XML documents (DOCX, PPTX, XLSX) locally.
+//
+// The text runs of the document parts are extracted, adjacent runs with identical formatting
+// are merged into a single segment and the translations are written back into the first run
+// of each group, so the formatting of every run survives the round trip.
+package ooxml
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	format "github.com/o0n1x/sublate-go/format"
+	"github.com/o0n1x/sublate-go/format/internal/archive"
+	"github.com/o0n1x/sublate-go/format/internal/xmltext"
+)
+
+// Supported reports whether the file name has an Office Open XML extension
+func Supported(filename string) bool {
+	d, _ := format.ByExtension(filepath.Ext(filename))
+	return d.MIME == format.DOCX.MIME || d.MIME == format.PPTX.MIME || d.MIME == format.XLSX.MIME
+}
+
+// markup describes how text runs are laid out in the parts of a document type
+type markup struct {
+	mime          string
+	prefix        string // namespace prefix of the elements below, so math runs (m:r) etc. are left alone
+	para          string // paragraph element, the scope in which runs are merged
+	run           string
+	props         string   // run properties, runs only merge if these are identical
+	text          string   // text element inside a run (or directly inside a paragraph)
+	barriers      []string // elements outside runs that stop runs from merging
+	preserveSpace bool     // text elements need xml:space="preserve" to keep leading/trailing spaces
+	part          func(name string) bool
+}
+
+var (
+	wordML = markup{
+		mime:          format.DOCX.MIME,
+		prefix:        "w",
+		para:          "p",
+		run:           "r",
+		props:         "rPr",
+		text:          "t",
+		barriers:      []string{"hyperlink", "fldSimple", "sdt"},
+		preserveSpace: true,
+		part: func(name string) bool {
+			if name == "word/document.xml" || name == "word/footnotes.xml" || name == "word/endnotes.xml" {
+				return true
+			}
+			base := path.Base(name)
+			return path.Dir(name) == "word" && (strings.HasPrefix(base, "header") || strings.HasPrefix(base, "footer")) && path.Ext(base) == ".xml"
+		},
+	}
+	presentationML = markup{
+		mime:     format.PPTX.MIME,
+		prefix:   "a",
+		para:     "p",
+		run:      "r",
+		props:    "rPr",
+		text:     "t",
+		barriers: []string{"br", "fld"},
+		part: func(name string) bool {
+			dir := path.Dir(name)
+			return (dir == "ppt/slides" || dir == "ppt/notesSlides") && path.Ext(name) == ".xml"
+		},
+	}
+	spreadsheetML = markup{
+		mime:          format.XLSX.MIME,
+		para:          "si",
+		run:           "r",
+		props:         "rPr",
+		text:          "t",
+		preserveSpace: true,
+		part: func(name string) bool {
+			return name == "xl/sharedStrings.xml"
+		},
+	}
+)
+
+func detect(files []archive.File) (markup, error) {
+	for _, f := range files {
+		switch f.Header.Name {
+		case "word/document.xml":
+			return wordML, nil
+		case "ppt/presentation.xml":
+			return presentationML, nil
+		case "xl/workbook.xml":
+			return spreadsheetML, nil
+		}
+	}
+	return markup{}, fmt.Errorf("ooxml: not a word, powerpoint or excel document")
+}
+
+// piece is the content of a single text element
+type piece struct {
+	props    string
+	text     string
+	start    int // content range of the text element
+	end      int
+	tag      xmltext.Token
+	separate bool // something other than a run boundary sits between this piece and the previous one
+}
+
+// group is a run of pieces merged into one segment. segment is -1 for whitespace only groups
+type group struct {
+	pieces  []piece
+	segment int
+}
+
+type part struct {
+	file   int
+	groups []group
+}
+
+// Skeleton holds the unzipped document and the location of every segment
+type Skeleton struct {
+	markup   markup
+	files    []archive.File
+	parts    []part
+	segments int
+}
+
+// MIME returns the media type of the extracted document
+func (sk *Skeleton) MIME() string {
+	return sk.markup.mime
+}
+
+// Extract unzips the document and returns the text of its merged runs in document order
+func Extract(data []byte) ([]string, *Skeleton, error) {
+	files, err := archive.Read(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ooxml: %w", err)
+	}
+	m, err := detect(files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sk := &Skeleton{markup: m, files: files}
+	var segments []string
+
+	for i, f := range files {
+		if !m.part(f.Header.Name) {
+			continue
+		}
+		groups, err := m.extractPart(f.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ooxml: %s: %w", f.Header.Name, err)
+		}
+		for g := range groups {
+			text := groups[g].text()
+			if strings.TrimSpace(text) == "" {
+				groups[g].segment = -1
+				continue
+			}
+			groups[g].segment = len(segments)
+			segments = append(segments, strings.TrimSpace(text))
+		}
+		sk.parts = append(sk.parts, part{file: i, groups: groups})
+	}
+
+	sk.segments = len(segments)
+	return segments, sk, nil
+}
+
+// paragraph is the extraction state of an open paragraph. paragraphs can nest (text boxes)
+type paragraph struct {
+	pieces     []piece
+	inRun      bool
+	props      string
+	propsStart int
+	inProps    bool
+	separate   bool
+}
+
+func (m markup) extractPart(data []byte) ([]group, error) {
+	tokens, err := xmltext.Tokenize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []group
+	var paras []*paragraph
+	var stack []string // local names of the open elements
+
+	var textTag *xmltext.Token
+	var text strings.Builder
+
+	for i, tok := range tokens {
+		var p *paragraph
+		if len(paras) > 0 {
+			p = paras[len(paras)-1]
+		}
+
+		switch tok.Kind {
+		case xmltext.StartElement:
+			local := m.local(tok)
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			stack = append(stack, local)
+
+			switch {
+			case p != nil && p.inProps:
+			case local == m.para:
+				paras = append(paras, &paragraph{})
+			case p == nil:
+			case local == m.run && !p.inRun:
+				p.inRun = true
+				p.props = ""
+			case local == m.props && p.inRun:
+				p.inProps = true
+				p.propsStart = tok.Start
+			case local == m.text && (p.inRun && parent == m.run || parent == m.para):
+				if !bytes.HasSuffix(data[tok.Start:tok.End], []byte("/>")) {
+					textTag = &tokens[i]
+					text.Reset()
+				}
+			case p.inRun:
+				// tabs, breaks, drawings... split the text of a run
+				p.separate = true
+			case slices.Contains(m.barriers, local):
+				p.separate = true
+			}
+
+		case xmltext.CharData:
+			if textTag != nil {
+				text.WriteString(tok.Text)
+			}
+
+		case xmltext.EndElement:
+			local := m.local(tok)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if p == nil {
+				continue
+			}
+
+			switch {
+			case p.inProps:
+				if local == m.props {
+					p.props = string(data[p.propsStart:tok.End])
+					p.inProps = false
+				}
+			case local == m.text && textTag != nil:
+				props := ""
+				if p.inRun {
+					props = p.props
+				}
+				p.pieces = append(p.pieces, piece{
+					props:    props,
+					text:     text.String(),
+					start:    textTag.End,
+					end:      tok.Start,
+					tag:      *textTag,
+					separate: p.separate,
+				})
+				p.separate = false
+				textTag = nil
+			case local == m.run && p.inRun:
+				p.inRun = false
+			case local == m.para:
+				groups = append(groups, p.groups()...)
+				paras = paras[:len(paras)-1]
+			case slices.Contains(m.barriers, local):
+				p.separate = true
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// local returns the local name of elements in the markup namespace, "" for others
+func (m markup) local(tok xmltext.Token) string {
+	if tok.Name.Space != m.prefix {
+		return ""
+	}
+	return tok.Name.Local
+}
+
+// groups merges consecutive pieces with the same formatting
+func (p *paragraph) groups() []group {
+	var groups []group
+	for i, pc := range p.pieces {
+		if i == 0 || pc.separate || pc.props != p.pieces[i-1].props {
+			groups = append(groups, group{})
+		}
+		g := &groups[len(groups)-1]
+		g.pieces = append(g.pieces, pc)
+	}
+	return groups
+}
+
+func (g group) text() string {
+	var b strings.Builder
+	for _, pc := range g.pieces {
+		b.WriteString(pc.text)
+	}
+	return b.String()
+}
+
+// Merge writes the translations into the first run of each group, empties the other runs of the group and rezips the document
+func Merge(sk *Skeleton, translated []string) ([]byte, error) {
+	if len(translated) != sk.segments {
+		return nil, fmt.Errorf("ooxml: got %d translations for %d segments", len(translated), sk.segments)
+	}
+
+	files := slices.Clone(sk.files)
+	for _, pt := range sk.parts {
+		var edits []xmltext.Edit
+		for _, g := range pt.groups {
+			if g.segment < 0 {
+				continue
+			}
+			text := g.text()
+			lead := text[:len(text)-len(strings.TrimLeft(text, " \t\r\n"))]
+			trail := text[len(strings.TrimRight(text, " \t\r\n")):]
+			result := lead + translated[g.segment] + trail
+
+			for i, pc := range g.pieces {
+				content := ""
+				if i == 0 {
+					content = result
+					if sk.markup.preserveSpace && result != strings.TrimSpace(result) {
+						if _, ok := pc.tag.AttrValue("xml", "space"); !ok {
+							edits = append(edits, xmltext.Edit{Start: pc.tag.Start, End: pc.tag.End, Text: preserveSpace(files[pt.file].Data[pc.tag.Start:pc.tag.End])})
+						}
+					}
+				}
+				edits = append(edits, xmltext.Edit{Start: pc.start, End: pc.end, Text: xmltext.Escape(content)})
+			}
+		}
+		files[pt.file].Data = xmltext.Apply(files[pt.file].Data, edits)
+	}
+
+	out, err := archive.Write(files)
+	if err != nil {
+		return nil, fmt.Errorf("ooxml: %w", err)
+	}
+	return out, nil
+}
+
+func preserveSpace(tag []byte) string {
+	return string(tag[:len(tag)-1]) + ` xml:space="preserve">`
+}
+
+// CharCount returns the number of characters in the merged runs of the document, what a provider is sent when the document is translated locally
+func CharCount(data []byte) (int, error) {
+	segments, _, err := Extract(data)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, s := range segments {
+		total += utf8.RuneCountInString(s)
+	}
+	return total, nil
+}
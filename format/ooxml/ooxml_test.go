@@ -0,0 +1,151 @@
+package ooxml
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readZipFile(t *testing.T, data []byte, name string) string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range r.File {
+		if f.Name == name {
+			rc, _ := f.Open()
+			defer rc.Close()
+			var b bytes.Buffer
+			b.ReadFrom(rc)
+			return b.String()
+		}
+	}
+	t.Fatalf("%s not found in output", name)
+	return ""
+}
+
+const wordDocument = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:m="http://schemas.openxmlformats.org/officeDocument/2006/math">
+<w:body>
+<w:p><w:r><w:rPr><w:i/></w:rPr><w:t>Hel</w:t></w:r><w:proofErr w:type="spellStart"/><w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">lo </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>world</w:t></w:r></w:p>
+<w:p><w:r><w:t>Name</w:t><w:tab/><w:t>Value &amp; more</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">   </w:t></w:r><m:oMath><m:r><m:t>x</m:t></m:r></m:oMath></w:p>
+</w:body>
+</w:document>`
+
+func TestExtractMerge(t *testing.T) {
+	cases := map[string]struct {
+		files      map[string]string
+		part       string
+		segments   []string
+		translated []string
+		contains   []string
+	}{
+		"docx": {
+			files: map[string]string{
+				"[Content_Types].xml": `<Types/>`,
+				"word/document.xml":   wordDocument,
+			},
+			part:       "word/document.xml",
+			segments:   []string{"Hello", "world", "Name", "Value & more"},
+			translated: []string{"Hallo", "Welt", "Name", "Wert & mehr"},
+			contains: []string{
+				`<w:rPr><w:i/></w:rPr><w:t xml:space="preserve">Hallo </w:t>`,
+				`<w:t xml:space="preserve"></w:t>`,
+				`<w:rPr><w:b/></w:rPr><w:t>Welt</w:t>`,
+				`<w:t>Name</w:t><w:tab/><w:t>Wert &amp; mehr</w:t>`,
+				`<m:t>x</m:t>`,
+			},
+		},
+		"pptx": {
+			files: map[string]string{
+				"ppt/presentation.xml":  `<p:presentation xmlns:p="p"/>`,
+				"ppt/slides/slide1.xml": `<p:sld xmlns:p="p" xmlns:a="a"><p:txBody><a:p><a:r><a:rPr lang="en-US"/><a:t>Quarterly</a:t></a:r><a:r><a:rPr lang="en-US"/><a:t> results</a:t></a:r><a:br/><a:r><a:rPr lang="en-US"/><a:t>Slide</a:t></a:r><a:fld type="slidenum"><a:t>1</a:t></a:fld></a:p></p:txBody></p:sld>`,
+			},
+			part:       "ppt/slides/slide1.xml",
+			segments:   []string{"Quarterly results", "Slide"},
+			translated: []string{"Quartalsergebnisse", "Folie"},
+			contains:   []string{`<a:t>Quartalsergebnisse</a:t>`, `<a:t></a:t>`, `<a:t>Folie</a:t>`, `<a:t>1</a:t>`},
+		},
+		"xlsx": {
+			files: map[string]string{
+				"xl/workbook.xml":      `<workbook/>`,
+				"xl/sharedStrings.xml": `<sst xmlns="main"><si><t>Total</t></si><si><r><rPr><b/></rPr><t>Net</t></r><r><t xml:space="preserve"> income</t></r></si></sst>`,
+			},
+			part:       "xl/sharedStrings.xml",
+			segments:   []string{"Total", "Net", "income"},
+			translated: []string{"Summe", "Netto", "einkommen"},
+			contains:   []string{`<si><t>Summe</t></si>`, `<rPr><b/></rPr><t>Netto</t>`, `<t xml:space="preserve"> einkommen</t>`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			segments, sk, err := Extract(buildZip(t, tc.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(segments, tc.segments) {
+				t.Fatalf("got segments %q, want %q", segments, tc.segments)
+			}
+
+			out, err := Merge(sk, tc.translated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part := readZipFile(t, out, tc.part)
+			for _, want := range tc.contains {
+				if !strings.Contains(part, want) {
+					t.Errorf("output does not contain %q:\n%s", want, part)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractInvalid(t *testing.T) {
+	cases := map[string][]byte{
+		"not a zip":     []byte("plain text"),
+		"unknown zip":   buildZip(t, map[string]string{"mimetype": "application/epub+zip"}),
+		"malformed xml": buildZip(t, map[string]string{"word/document.xml": "<w:document><w:body>"}),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := Extract(data); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestCharCount(t *testing.T) {
+	data := buildZip(t, map[string]string{"word/document.xml": wordDocument})
+	count, err := CharCount(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len("Hello") + len("world") + len("Name") + len("Value & more"); count != want {
+		t.Errorf("got %d, want %d", count, want)
+	}
+}
@@ -0,0 +1,106 @@
+package format
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// Segment is a piece of translatable text extracted from a document
+type Segment struct {
+	Text string
+}
+
+// Skeleton is the handler specific remainder of a document once its segments are extracted
+type Skeleton any
+
+// Handler parses a file type locally so only its text has to be sent to a provider.
+// handlers self register via init() just like providers do.
+type Handler interface {
+	MIME() string
+	Extensions() []string
+	Extract(doc []byte) ([]Segment, Skeleton, error)
+	Merge(sk Skeleton, translated []string, to lang.Language) ([]byte, error)
+}
+
+// MarkupHandler is implemented by handlers whose segments are xml fragments keeping inline tags.
+// their segments are translated with xml tag handling
+type MarkupHandler interface {
+	Handler
+	// IgnoreTags returns the elements whose content is left untranslated
+	IgnoreTags() []string
+}
+
+// KindHandler is implemented by handlers that declare the kind of a file type that is not known yet.
+// new file types default to KindLocal
+type KindHandler interface {
+	Handler
+	Kind() Kind
+}
+
+// CountingHandler is implemented by handlers that count the characters of a file without keeping its skeleton
+type CountingHandler interface {
+	Handler
+	CharCount(doc []byte) (int, error)
+}
+
+var handlers = map[string]Handler{}
+
+// Register registers a handler for its MIME type. file types that are not known yet become detectable by their extensions.
+// it panics if a handler is registered twice for a MIME type, like provider.Register
+func Register(h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := handlers[h.MIME()]; dup {
+		panic("format: Register called twice for " + h.MIME())
+	}
+	handlers[h.MIME()] = h
+
+	if _, ok := byMIME(h.MIME()); ok {
+		return
+	}
+	d := Descriptor{MIME: h.MIME(), Extensions: h.Extensions(), Kind: KindLocal, Binary: true}
+	if k, ok := h.(KindHandler); ok {
+		d.Kind = k.Kind()
+	}
+	if ext := d.Extension(); ext != "" {
+		d.Name = strings.TrimPrefix(ext, ".")
+	} else {
+		d.Name = h.MIME()
+	}
+	descriptors = append(descriptors, d)
+}
+
+// Lookup returns the handler registered for a file type
+func Lookup(d Descriptor) (Handler, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	h, ok := handlers[d.MIME]
+	return h, ok
+}
+
+// CharCount returns the number of characters in the segments of doc, the text a provider is sent when the file is parsed locally
+func CharCount(h Handler, doc []byte) (int, error) {
+	if c, ok := h.(CountingHandler); ok {
+		return c.CharCount(doc)
+	}
+	segments, _, err := h.Extract(doc)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range segments {
+		n += utf8.RuneCountInString(s.Text)
+	}
+	return n, nil
+}
+
+// Segments wraps plain strings as segments
+func Segments(texts []string) []Segment {
+	segments := make([]Segment, len(texts))
+	for i, t := range texts {
+		segments[i] = Segment{Text: t}
+	}
+	return segments
+}
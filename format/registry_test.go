@@ -0,0 +1,75 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// linesHandler translates every line of a file
+type linesHandler struct{}
+
+func (linesHandler) MIME() string         { return "text/x-lines" }
+func (linesHandler) Extensions() []string { return []string{".lines"} }
+
+func (linesHandler) Extract(doc []byte) ([]Segment, Skeleton, error) {
+	return Segments(strings.Split(string(doc), "\n")), nil, nil
+}
+
+func (linesHandler) Merge(_ Skeleton, translated []string, _ lang.Language) ([]byte, error) {
+	return []byte(strings.Join(translated, "\n")), nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(linesHandler{})
+
+	d := Detect("notes.LINES", []byte{0xff, 0x00})
+	if d.MIME != "text/x-lines" || d.Name != "lines" || d.Kind != KindLocal {
+		t.Fatalf("registered extension not detected, got %+v", d)
+	}
+	h, ok := Lookup(d)
+	if !ok {
+		t.Fatal("handler not found")
+	}
+	segments, sk, err := h.Extract([]byte("a\nb"))
+	if err != nil || len(segments) != 2 {
+		t.Fatalf("got %v, %v", segments, err)
+	}
+	out, err := h.Merge(sk, []string{"A", "B"}, lang.German)
+	if err != nil || string(out) != "A\nB" {
+		t.Errorf("got %q, %v", out, err)
+	}
+
+	if _, ok := Lookup(PDF); ok {
+		t.Error("no handler should be registered for pdf")
+	}
+}
+
+// pagesHandler declares its file type as a document
+type pagesHandler struct{ linesHandler }
+
+func (pagesHandler) MIME() string         { return "application/x-pages" }
+func (pagesHandler) Extensions() []string { return []string{".pages"} }
+func (pagesHandler) Kind() Kind           { return KindDocument }
+
+func TestRegisterKind(t *testing.T) {
+	Register(pagesHandler{})
+	if d, _ := ByExtension(".pages"); d.Kind != KindDocument {
+		t.Errorf("got kind %v, want the declared KindDocument", d.Kind)
+	}
+}
+
+type twiceHandler struct{ linesHandler }
+
+func (twiceHandler) MIME() string { return "text/x-twice" }
+
+func TestRegisterTwice(t *testing.T) {
+	Register(twiceHandler{})
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	Register(twiceHandler{})
+}
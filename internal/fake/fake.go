@@ -0,0 +1,32 @@
+// Package fake holds the fake clients shared by the tests of the module
+package fake
+
+import (
+	"context"
+	"strings"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// Upper is a fake SyncClient that "translates" by upper casing the text, it records the requests it gets
+type Upper struct {
+	Requests []provider.Request
+}
+
+func (c *Upper) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	c.Requests = append(c.Requests, req)
+	res := provider.Response{Provider: c.Name()}
+	for _, s := range req.Text {
+		res.Text = append(res.Text, strings.ToUpper(s))
+		res.Segments = append(res.Segments, provider.SegmentMeta{DetectedLanguage: lang.English, BilledCharacters: len(s)})
+		res.BilledCharacters += len(s)
+	}
+	return res, nil
+}
+
+func (c *Upper) GetCost(provider.Request) float32    { return 0 }
+func (c *Upper) GetCharCount(provider.Request) int   { return 0 }
+func (c *Upper) Capabilities() provider.Capabilities { return provider.Capabilities{} }
+func (c *Upper) Name() provider.Provider             { return "upper" }
+func (c *Upper) Version() string                     { return "test" }
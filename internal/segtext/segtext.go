@@ -0,0 +1,17 @@
+// Package segtext holds the text helpers shared by the clients answering segments without the provider
+package segtext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Rewrap surrounds a stored translation with the whitespace of the original segment
+func Rewrap(original, translation string) string {
+	start := len(original) - len(strings.TrimLeftFunc(original, unicode.IsSpace))
+	end := len(strings.TrimRightFunc(original, unicode.IsSpace))
+	if end < start {
+		return original
+	}
+	return original[:start] + translation + original[end:]
+}
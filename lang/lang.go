@@ -57,3 +57,17 @@ func (l Language) Lower() string {
 func (l Language) String() string {
 	return string(l)
 }
+
+// BCP47 returns the language as a BCP 47 tag. ex: PT-BR -> pt-BR, ZH-HANS -> zh-Hans
+func (l Language) BCP47() string {
+	parts := strings.Split(string(l), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 4 { // script subtag
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		} else {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,67 @@
+package lang
+
+import "strings"
+
+// PluralCategory is a CLDR plural category used by ICU plural messages.
+type PluralCategory string
+
+const (
+	Zero  PluralCategory = "zero"
+	One   PluralCategory = "one"
+	Two   PluralCategory = "two"
+	Few   PluralCategory = "few"
+	Many  PluralCategory = "many"
+	Other PluralCategory = "other"
+)
+
+// cardinal plural categories per base language (CLDR 44), in CLDR order
+var pluralCategories = map[string][]PluralCategory{
+	"ar": {Zero, One, Two, Few, Many, Other},
+	"bg": {One, Other},
+	"cs": {One, Few, Many, Other},
+	"da": {One, Other},
+	"de": {One, Other},
+	"el": {One, Other},
+	"en": {One, Other},
+	"es": {One, Many, Other},
+	"et": {One, Other},
+	"fi": {One, Other},
+	"fr": {One, Many, Other},
+	"he": {One, Two, Other},
+	"hu": {One, Other},
+	"id": {Other},
+	"it": {One, Many, Other},
+	"ja": {Other},
+	"ko": {Other},
+	"lt": {One, Few, Many, Other},
+	"lv": {Zero, One, Other},
+	"nb": {One, Other},
+	"nl": {One, Other},
+	"pl": {One, Few, Many, Other},
+	"pt": {One, Many, Other},
+	"ro": {One, Few, Other},
+	"ru": {One, Few, Many, Other},
+	"sk": {One, Few, Many, Other},
+	"sl": {One, Two, Few, Other},
+	"sv": {One, Other},
+	"th": {Other},
+	"tr": {One, Other},
+	"uk": {One, Few, Many, Other},
+	"vi": {Other},
+	"zh": {Other},
+}
+
+// Base returns the language without its regional or script subtag. ex: PT-BR -> PT
+func (l Language) Base() Language {
+	base, _, _ := strings.Cut(string(l), "-")
+	return Language(base)
+}
+
+// PluralCategories returns the CLDR cardinal plural categories of the language.
+// unknown languages fall back to {one, other}
+func (l Language) PluralCategories() []PluralCategory {
+	if cats, ok := pluralCategories[l.Base().Lower()]; ok {
+		return cats
+	}
+	return []PluralCategory{One, Other}
+}
@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls go through
+	BreakerOpen                         // calls fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // one probe call goes through, it closes or reopens the circuit
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// Breaker is a circuit breaker opening after consecutive network errors or 5xx responses of a provider,
+// so calls fail fast while it is down instead of waiting for timeouts. ErrCircuitOpen is retryable: Fallback moves on to the next client
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	onChange  func(from, to BreakerState)
+	now       func() time.Time
+
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+type BreakerOption func(*Breaker)
+
+// OnStateChange calls f on every state change, ex: to log or alert. f must not block
+func OnStateChange(f func(from, to BreakerState)) BreakerOption {
+	return func(b *Breaker) { b.onChange = f }
+}
+
+// NewBreaker returns a breaker opening after threshold consecutive failures (at least 1) and probing the provider after cooldown
+func NewBreaker(threshold int, cooldown time.Duration, opts ...BreakerOption) *Breaker {
+	b := &Breaker{threshold: max(threshold, 1), cooldown: cooldown, now: time.Now}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// State returns the state of the circuit, an open circuit past its cooldown is reported half-open
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && !b.now().Before(b.openedAt.Add(b.cooldown)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a call may go through, probe is set for the call testing a half-open circuit
+func (b *Breaker) allow() (probe bool, wait time.Duration, ok bool) {
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case BreakerClosed:
+		ok = true
+	case BreakerOpen:
+		if wait = b.openedAt.Add(b.cooldown).Sub(b.now()); wait > 0 {
+			break
+		}
+		b.state = BreakerHalfOpen
+		fallthrough
+	case BreakerHalfOpen:
+		if !b.probing {
+			b.probing, probe, ok = true, true, true
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return probe, wait, ok
+}
+
+// record updates the circuit with the result of a call
+func (b *Breaker) record(probe bool, err error) {
+	b.mu.Lock()
+	from := b.state
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case tripping(err):
+		b.failures++
+		if probe || b.failures >= b.threshold {
+			b.state, b.openedAt = BreakerOpen, b.now()
+		}
+	default:
+		// the provider answered
+		b.failures = 0
+		if probe {
+			b.state = BreakerClosed
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *Breaker) release(probe bool) {
+	if probe {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+	}
+}
+
+func (b *Breaker) notify(from, to BreakerState) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
+
+// tripping reports whether err means the provider is down: network errors, client timeouts included, and 5xx responses
+func tripping(err error) bool {
+	if err == nil {
+		return false
+	}
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) {
+		return false
+	}
+	switch terr.Code {
+	case serr.ErrNetwork:
+		return true
+	case serr.ErrHTTP:
+		var herr *serr.HTTPError
+		return errors.As(err, &herr) && herr.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// CircuitBreaking guards the calls of a client with b, share b between the sync and async sides of a client
+func CircuitBreaking(b *Breaker) Middleware {
+	return func(next SyncClient) SyncClient {
+		return breakingClient{next, b}
+	}
+}
+
+func CircuitBreakingAsync(b *Breaker) AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return breakingAsyncClient{next, b}
+	}
+}
+
+// guard runs call through b. calls ended by ctx, canceled or past its deadline, and calls a limiter had no turn for
+// before that deadline tell nothing about the provider and are not recorded
+func guard(ctx context.Context, b *Breaker, op string, client Client, call func() error) error {
+	probe, wait, ok := b.allow()
+	if !ok {
+		if wait > 0 {
+			return serr.New(serr.ErrCircuitOpen, op, string(client.Name()), fmt.Errorf("circuit open, next probe in %v", wait.Round(time.Millisecond)))
+		}
+		return serr.New(serr.ErrCircuitOpen, op, string(client.Name()), fmt.Errorf("circuit half-open, waiting for the probe"))
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			// call panicked, let the next call probe
+			b.release(probe)
+		}
+	}()
+	err := call()
+	completed = true
+	if ctx.Err() != nil || errors.Is(err, ErrDeadlineTooClose) {
+		// the provider was not reached, the next call probes again
+		b.release(probe)
+		return err
+	}
+	b.record(probe, err)
+	return err
+}
+
+type breakingClient struct {
+	SyncClient
+	breaker *Breaker
+}
+
+func (c breakingClient) Unwrap() Client { return c.SyncClient }
+
+func (c breakingClient) Translate(ctx context.Context, req Request) (res Response, err error) {
+	err = guard(ctx, c.breaker, "Translate", c, func() error {
+		res, err = c.SyncClient.Translate(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+type breakingAsyncClient struct {
+	AsyncClient
+	breaker *Breaker
+}
+
+func (c breakingAsyncClient) Unwrap() Client { return c.AsyncClient }
+
+func (c breakingAsyncClient) AsyncTranslate(ctx context.Context, req Request) (res AsyncResponse, err error) {
+	err = guard(ctx, c.breaker, "AsyncTranslate", c, func() error {
+		res, err = c.AsyncClient.AsyncTranslate(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+func (c breakingAsyncClient) CheckStatus(ctx context.Context, obj AsyncResponse) (res JobStatus, err error) {
+	err = guard(ctx, c.breaker, "CheckStatus", c, func() error {
+		res, err = c.AsyncClient.CheckStatus(ctx, obj)
+		return err
+	})
+	return res, err
+}
+
+func (c breakingAsyncClient) GetResult(ctx context.Context, obj AsyncResponse) (res Response, err error) {
+	err = guard(ctx, c.breaker, "GetResult", c, func() error {
+		res, err = c.AsyncClient.GetResult(ctx, obj)
+		return err
+	})
+	return res, err
+}
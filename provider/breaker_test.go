@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+// httpClient is an echoClient posting its requests to url with client
+type httpClient struct {
+	echoClient
+	url    string
+	client *http.Client
+}
+
+func (c *httpClient) Translate(ctx context.Context, req Request) (Response, error) {
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, nil)
+	if err != nil {
+		return Response{}, serr.New(serr.ErrInvalidRequest, "Translate", "stub", err)
+	}
+	resp, err := c.client.Do(hreq)
+	if err != nil {
+		return Response{}, serr.New(serr.ErrNetwork, "Translate", "stub", err)
+	}
+	resp.Body.Close()
+	return Response{Text: req.Text}, nil
+}
+
+// failingClient is an echoClient failing with err
+type failingClient struct {
+	echoClient
+	err error
+}
+
+func (c *failingClient) Translate(ctx context.Context, req Request) (Response, error) {
+	c.record("Translate")
+	return Response{}, c.err
+}
+
+func TestTripping(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"success":        {nil, false},
+		"network":        {serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("connection refused")), true},
+		"server error":   {serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 503}), true},
+		"bad request":    {serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 400}), false},
+		"rate limited":   {serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 429}), false},
+		"quota":          {serr.New(serr.ErrQuotaExceeded, "Translate", "stub", nil), false},
+		"client timeout": {serr.New(serr.ErrNetwork, "Translate", "stub", fmt.Errorf("Post: %w", context.DeadlineExceeded)), true},
+		"not sublaterr":  {errors.New("boom"), false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tripping(tc.err); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var changes []string
+	b := NewBreaker(3, time.Minute, OnStateChange(func(from, to BreakerState) {
+		changes = append(changes, fmt.Sprintf("%s->%s", from, to))
+	}))
+	b.now = func() time.Time { return now }
+
+	down := serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("connection refused"))
+	inner := &failingClient{err: down}
+	client := CircuitBreaking(b)(inner)
+	ctx := context.Background()
+	call := func() error {
+		_, err := client.Translate(ctx, Request{Text: []string{"hi"}})
+		return err
+	}
+	isOpen := func(err error) bool {
+		var terr *serr.TranslateError
+		return errors.As(err, &terr) && terr.Code == serr.ErrCircuitOpen
+	}
+
+	for range 3 {
+		if err := call(); !errors.Is(err, down) {
+			t.Fatalf("expected the provider error, got %v", err)
+		}
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("circuit should open after 3 failures, got %s", b.State())
+	}
+	if err := call(); !isOpen(err) || !serr.Retryable(err) || len(inner.calls) != 3 {
+		t.Fatalf("open circuit should fail fast with a retryable ErrCircuitOpen, got %v after %d calls", err, len(inner.calls))
+	}
+
+	// a failing probe reopens the circuit
+	now = now.Add(time.Minute)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected half-open after the cooldown, got %s", b.State())
+	}
+	if err := call(); !errors.Is(err, down) {
+		t.Fatalf("the probe should reach the provider, got %v", err)
+	}
+	if err := call(); !isOpen(err) {
+		t.Fatalf("failed probe should reopen the circuit, got %v", err)
+	}
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	inner.err = nil
+	if err := call(); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got changes %v, want %v", changes, want)
+	}
+}
+
+func TestBreakerResets(t *testing.T) {
+	b := NewBreaker(2, time.Minute)
+	down := serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 502})
+	invalid := serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 400})
+
+	// only consecutive failures count, any answer of the provider resets them
+	for _, err := range []error{down, invalid, down, nil, down} {
+		probe, _, ok := b.allow()
+		if !ok {
+			t.Fatal("circuit opened without consecutive failures")
+		}
+		b.record(probe, err)
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("expected closed, got %s", b.State())
+	}
+}
+
+func TestBreakerShared(t *testing.T) {
+	b := NewBreaker(1, time.Hour)
+	client := Wrap(&failingClient{err: serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("timeout"))}, CircuitBreaking(b), CircuitBreakingAsync(b))
+	syncC := client.(SyncClient)
+
+	syncC.Translate(context.Background(), Request{})
+	_, err := client.(AsyncClient).CheckStatus(context.Background(), AsyncResponse{})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrCircuitOpen {
+		t.Errorf("the async side should share the breaker, got %v", err)
+	}
+}
+
+// slowServer answers after 300ms
+func slowServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBreakerClientTimeout(t *testing.T) {
+	srv := slowServer(t)
+	b := NewBreaker(1, time.Minute)
+	client := CircuitBreaking(b)(&httpClient{url: srv.URL, client: &http.Client{Timeout: 50 * time.Millisecond}})
+
+	_, err := client.Translate(context.Background(), Request{Text: []string{"hi"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the client timeout, got %v", err)
+	}
+	if b.State() != BreakerOpen {
+		t.Errorf("client timeouts should trip the breaker, got %s", b.State())
+	}
+}
+
+func TestBreakerCallerDeadline(t *testing.T) {
+	srv := slowServer(t)
+	b := NewBreaker(1, time.Minute)
+	client := CircuitBreaking(b)(&httpClient{url: srv.URL, client: srv.Client()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Translate(ctx, Request{Text: []string{"hi"}}); err == nil {
+		t.Fatal("expected the deadline of the caller")
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("the deadline of the caller should not trip the breaker, got %s", b.State())
+	}
+}
+
+func TestBreakerRateLimited(t *testing.T) {
+	b := NewBreaker(1, time.Minute)
+	l := NewLimiter(RateLimit{Requests: Rate{1, time.Hour}})
+	client := Wrap(&echoClient{}, Chain(CircuitBreaking(b), RateLimiting(l)), nil).(SyncClient)
+
+	if _, err := client.Translate(context.Background(), Request{Text: []string{"hi"}}); err != nil {
+		t.Fatal(err)
+	}
+	// the next turn is in an hour, past the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for range 3 {
+		_, err := client.Translate(ctx, Request{Text: []string{"hi"}})
+		var terr *serr.TranslateError
+		if !errors.As(err, &terr) || terr.Code != serr.ErrThrottled || serr.Retryable(err) {
+			t.Fatalf("expected a throttled call, got %v", err)
+		}
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("throttled calls should not trip the breaker, got %s", b.State())
+	}
+
+	// a throttled probe lets the next call probe
+	b.record(false, serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("connection refused")))
+	b.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if _, err := client.Translate(ctx, Request{Text: []string{"hi"}}); !errors.Is(err, ErrDeadlineTooClose) {
+		t.Fatalf("expected a throttled probe, got %v", err)
+	}
+	if probe, _, ok := b.allow(); !probe || !ok {
+		t.Errorf("the throttled probe should not hold the circuit, state %s", b.State())
+	}
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// Capabilities describes what a client supports so requests can be validated before any network call.
+// the zero value is unrestricted: empty lists, zero limits and unset restrictions allow anything, features are only supported when set
+type Capabilities struct {
+	Formats         []format.Format
+	SourceLanguages []lang.Language
+	TargetLanguages []lang.Language
+
+	MaxBatchSize    int   // max number of texts in a text request
+	MaxRequestSize  int   // max total size in bytes of the texts of a text request
+	MaxDocumentSize int64 // max size in bytes of a document
+
+	Glossaries   bool
+	Formality    bool
+	NoAutoDetect bool // the source language must be set, the provider can't detect it
+}
+
+func (c Capabilities) SupportsFormat(f format.Format) bool {
+	return len(c.Formats) == 0 || slices.Contains(c.Formats, f)
+}
+
+// SupportsSource reports whether l can be translated from. "" and AutoDetect are rejected with NoAutoDetect
+func (c Capabilities) SupportsSource(l lang.Language) bool {
+	if l == "" || l == lang.AutoDetect {
+		return !c.NoAutoDetect
+	}
+	return len(c.SourceLanguages) == 0 || slices.Contains(c.SourceLanguages, l)
+}
+
+func (c Capabilities) SupportsTarget(l lang.Language) bool {
+	return len(c.TargetLanguages) == 0 || slices.Contains(c.TargetLanguages, l)
+}
+
+// CheckCapabilities validates a request against the capabilities of the client before anything is sent.
+// reqType is the format the provider receives, text for files translated locally
+func CheckCapabilities(req Request, reqType format.Format, client Client) error {
+	caps := client.Capabilities()
+	name := string(client.Name())
+
+	switch {
+	case !caps.SupportsFormat(reqType):
+		return serr.New(serr.ErrInvalidRequest, "CheckCapabilities", name, fmt.Errorf("%s requests are not supported", reqType))
+	case !caps.SupportsSource(req.From):
+		return serr.New(serr.ErrInvalidLanguage, "CheckCapabilities", name, fmt.Errorf("source language %q is not supported", req.From))
+	case !caps.SupportsTarget(req.To):
+		return serr.New(serr.ErrInvalidLanguage, "CheckCapabilities", name, fmt.Errorf("target language %q is not supported", req.To))
+	case reqType == format.File && caps.MaxDocumentSize > 0 && int64(len(req.Binary)) > caps.MaxDocumentSize:
+		return serr.New(serr.ErrInvalidRequest, "CheckCapabilities", name, fmt.Errorf("document is %d bytes, the limit is %d", len(req.Binary), caps.MaxDocumentSize))
+	case req.GlossaryID != "" && !caps.Glossaries:
+		return serr.New(serr.ErrUnsupportedOption, "CheckCapabilities", name, &UnsupportedOptionError{Option: "GlossaryID", Value: req.GlossaryID, Reason: "glossaries are not supported"})
+	case req.Options.Formality != FormalityDefault && !caps.Formality:
+		return serr.New(serr.ErrUnsupportedOption, "CheckCapabilities", name, &UnsupportedOptionError{Option: "Formality", Value: req.Options.Formality, Reason: "formality is not supported"})
+	}
+	return nil
+}
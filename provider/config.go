@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"maps"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the request timeout of the http clients built by providers when none is set
+const DefaultTimeout = time.Minute
+
+// Config is the client configuration given to a ClientFactory. build it with ClientOptions
+type Config struct {
+	APIKey     string
+	HTTPClient *http.Client // nil for a new client
+	BaseURL    string       // replaces the API host of the provider, ex: a proxy. "" keeps the default
+	Timeout    time.Duration
+	UserAgent  string
+	Headers    http.Header // sent with every request
+	Limiter    *Limiter    // throttles the client, applied by GetClient. nil for no limit
+	Retry      Retry       // retries failed calls, applied by GetClient
+}
+
+type ClientOption func(*Config)
+
+func WithAPIKey(apiKey string) ClientOption {
+	return func(c *Config) { c.APIKey = apiKey }
+}
+
+// WithHTTPClient makes the client send its requests through hc
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Config) { c.HTTPClient = hc }
+}
+
+// WithTransport sets the transport of the http client, ex: for proxies or custom TLS
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Config) {
+		hc := &http.Client{Timeout: DefaultTimeout}
+		if c.HTTPClient != nil {
+			*hc = *c.HTTPClient
+		}
+		hc.Transport = rt
+		c.HTTPClient = hc
+	}
+}
+
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Config) { c.BaseURL = baseURL }
+}
+
+// WithTimeout sets the timeout of each http request, overriding the one of a custom http client
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Config) { c.Timeout = timeout }
+}
+
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Config) { c.UserAgent = userAgent }
+}
+
+// WithHeader adds a header sent with every request
+func WithHeader(key, value string) ClientOption {
+	return func(c *Config) {
+		if c.Headers == nil {
+			c.Headers = http.Header{}
+		}
+		c.Headers.Add(key, value)
+	}
+}
+
+// WithRateLimit throttles the client, see RateLimit. clients created with the same option don't share their limit, use WithLimiter for that
+func WithRateLimit(limit RateLimit) ClientOption {
+	return func(c *Config) { c.Limiter = NewLimiter(limit) }
+}
+
+// WithLimiter throttles the client with l, ex: to share the limits of an account between several clients
+func WithLimiter(l *Limiter) ClientOption {
+	return func(c *Config) { c.Limiter = l }
+}
+
+// WithRetry retries the calls of the client failing with a transient error, see Retry
+func WithRetry(r Retry) ClientOption {
+	return func(c *Config) { c.Retry = r }
+}
+
+func NewConfig(opts ...ClientOption) Config {
+	cfg := Config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// NewHTTPClient returns the http client described by the config.
+// providers use it so the user agent, headers and timeout options behave the same everywhere
+func (c Config) NewHTTPClient() *http.Client {
+	hc := &http.Client{Timeout: DefaultTimeout}
+	if c.HTTPClient != nil {
+		*hc = *c.HTTPClient
+	}
+	if c.Timeout > 0 {
+		hc.Timeout = c.Timeout
+	}
+	if c.UserAgent != "" || len(c.Headers) > 0 {
+		base := hc.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		hc.Transport = &headerTransport{base: base, userAgent: c.UserAgent, headers: c.Headers}
+	}
+	return hc
+}
+
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	maps.Copy(req.Header, t.headers)
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
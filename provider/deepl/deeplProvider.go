@@ -7,24 +7,28 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
 	serr "github.com/o0n1x/sublate-go/errors"
 	format "github.com/o0n1x/sublate-go/format"
+	"github.com/o0n1x/sublate-go/format/ooxml"
 	lang "github.com/o0n1x/sublate-go/lang"
 	provider "github.com/o0n1x/sublate-go/provider"
 )
 
 func init() {
-	provider.Register(provider.DeepL, func(apiKey string) provider.Client {
-		return GetDeeplClient(apiKey)
+	provider.Register(provider.DeepL, func(cfg provider.Config) (provider.Client, error) {
+		return NewClient(cfg)
 	})
 }
 
+// static language lists, used when the languages can't be fetched from DeepL
 var SupportedFromLang = map[lang.Language]bool{
 	lang.AutoDetect:      true,
 	lang.Arabic:          true,
@@ -112,6 +116,8 @@ type Translations struct {
 	Translations []struct {
 		DetectedSourceLanguage string `json:"detected_source_language"`
 		Text                   string `json:"text"`
+		BilledCharacters       int    `json:"billed_characters"`
+		ModelTypeUsed          string `json:"model_type_used"`
 	} `json:"translations"`
 }
 
@@ -125,6 +131,8 @@ type DeepLClient struct {
 	BaseURL *url.URL
 	APIKey  string
 	IsFree  bool
+
+	languages languageCache
 }
 
 const (
@@ -146,26 +154,47 @@ func apiHost(authKey string) string {
 }
 
 func GetDeeplClient(apiKey string) *DeepLClient {
-	u, _ := url.Parse(apiHost(apiKey))
-	baseURL := u.JoinPath(APIVersion)
-	return &DeepLClient{
-		Client:  &http.Client{},
-		BaseURL: baseURL,
-		APIKey:  apiKey,
-		IsFree:  isFreeAccount(apiKey),
+	c, _ := NewClient(provider.Config{APIKey: apiKey}) // the default host always parses
+	return c
+}
+
+// NewClient builds a client from the config. the API version is appended to a custom BaseURL
+func NewClient(cfg provider.Config) (*DeepLClient, error) {
+	host := cfg.BaseURL
+	if host == "" {
+		host = apiHost(cfg.APIKey)
+	}
+	u, err := url.Parse(host)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, serr.New(serr.ErrInvalidRequest, "NewClient", string(provider.DeepL), fmt.Errorf("invalid base url %q", host))
 	}
+
+	return &DeepLClient{
+		Client:  cfg.NewHTTPClient(),
+		BaseURL: u.JoinPath(APIVersion),
+		APIKey:  cfg.APIKey,
+		IsFree:  isFreeAccount(cfg.APIKey),
+	}, nil
 }
 
 // will verify the input like from/to lang is valid and use the appropriate helper function to get translation
 func (c *DeepLClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
 	//validate lang
-	req, err := validateRequest(req)
+	req, err := c.validateRequest(ctx, req)
 	if err != nil {
 		return provider.Response{}, err
 	}
 
+	if err := checkOptions(req); err != nil {
+		return provider.Response{}, err
+	}
+
+	if err := c.checkGlossary(ctx, req); err != nil {
+		return provider.Response{}, err
+	}
+
 	if req.ReqType == format.Text {
-		return c.translateText(ctx, req.Text, req.From, req.To)
+		return c.translateText(ctx, req)
 	} else {
 		return provider.Response{}, serr.New(serr.ErrInvalidRequest, "Translate", string(provider.DeepL), fmt.Errorf("Invalid Request Type %v", req.ReqType.String()))
 	}
@@ -173,27 +202,37 @@ func (c *DeepLClient) Translate(ctx context.Context, req provider.Request) (prov
 }
 
 func (c *DeepLClient) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
-	req, err := validateRequest(req)
+	req, err := c.validateRequest(ctx, req)
 	if err != nil {
 		return provider.AsyncResponse{}, err
 	}
 
+	if err := checkOptions(req); err != nil {
+		return provider.AsyncResponse{}, err
+	}
+
+	if err := c.checkGlossary(ctx, req); err != nil {
+		return provider.AsyncResponse{}, err
+	}
+
 	if req.ReqType == format.File {
-		return c.translateDoc(ctx, req.Binary, req.FileName, req.From, req.To)
+		return c.translateDoc(ctx, req)
 	} else {
 		return provider.AsyncResponse{}, serr.New(serr.ErrInvalidRequest, "AsyncTranslate", string(provider.DeepL), fmt.Errorf("Invalid Request Type %v", req.ReqType.String()))
 	}
 
 }
 
-func validateRequest(req provider.Request) (provider.Request, *serr.TranslateError) {
+// validateRequest checks the request against the languages DeepL currently supports, or the static maps when offline
+func (c *DeepLClient) validateRequest(ctx context.Context, req provider.Request) (provider.Request, *serr.TranslateError) {
 	if req.From == "" {
 		req.From = lang.AutoDetect
 	}
-	if !SupportedFromLang[req.From] {
+	supportedFrom, supportedTo, _ := c.languageLists(ctx)
+	if !supportedFrom[req.From] {
 		return req, serr.New(serr.ErrInvalidLanguage, "validateRequest", string(provider.DeepL), fmt.Errorf("Invalid Source Language %v", req.From))
 	}
-	if !SupportedToLang[req.To] {
+	if !supportedTo[req.To] {
 		return req, serr.New(serr.ErrInvalidLanguage, "validateRequest", string(provider.DeepL), fmt.Errorf("Invalid Target Language %v", req.To))
 	}
 
@@ -232,11 +271,42 @@ func (c *DeepLClient) GetCharCount(req provider.Request) int {
 			totalChars += count
 		}
 		return totalChars
+	case format.File:
+		// office documents are counted per text run, other files can't be estimated
+		if ooxml.Supported(req.FileName) {
+			count, err := ooxml.CharCount(req.Binary)
+			if err == nil {
+				return count
+			}
+		}
+		return 0
 	default:
 		return 0
 	}
 }
 
+const (
+	MaxBatchSize    = 50
+	MaxRequestSize  = 120 * 1024       // the API accepts 128 KiB, the rest is left for the other parameters
+	MaxDocumentSize = 30 * 1024 * 1024 // upload limit, some file types have lower limits
+)
+
+// Capabilities uses the languages fetched from DeepL if they are cached. until then languages aren't restricted,
+// requests are still validated against the live list before anything is sent
+func (c *DeepLClient) Capabilities() provider.Capabilities {
+	source, target := c.cachedLanguages()
+	return provider.Capabilities{
+		Formats:         slices.Sorted(maps.Keys(SupportedFormats)),
+		SourceLanguages: sortedLanguages(source),
+		TargetLanguages: sortedLanguages(target),
+		MaxBatchSize:    MaxBatchSize,
+		MaxRequestSize:  MaxRequestSize,
+		MaxDocumentSize: MaxDocumentSize,
+		Glossaries:      true,
+		Formality:       true,
+	}
+}
+
 func (c *DeepLClient) Name() provider.Provider {
 	return provider.DeepL
 }
@@ -245,29 +315,57 @@ func (c *DeepLClient) Version() string {
 	return APIVersion
 }
 
-func (c *DeepLClient) translateText(ctx context.Context, text []string, from lang.Language, to lang.Language) (provider.Response, error) {
+func (c *DeepLClient) translateText(ctx context.Context, r provider.Request) (provider.Response, error) {
 
 	params := struct {
-		Text       []string `json:"text"`
-		TargetLang string   `json:"target_lang"`
-		SourceLang string   `json:"source_lang,omitempty"`
+		Text               []string `json:"text"`
+		TargetLang         string   `json:"target_lang"`
+		SourceLang         string   `json:"source_lang,omitempty"`
+		GlossaryID         string   `json:"glossary_id,omitempty"`
+		Formality          string   `json:"formality,omitempty"`
+		Context            string   `json:"context,omitempty"`
+		PreserveFormatting bool     `json:"preserve_formatting,omitempty"`
+		SplitSentences     string   `json:"split_sentences,omitempty"`
+		ModelType          string   `json:"model_type,omitempty"`
+		TagHandling        string   `json:"tag_handling,omitempty"`
+		OutlineDetection   *bool    `json:"outline_detection,omitempty"`
+		NonSplittingTags   []string `json:"non_splitting_tags,omitempty"`
+		SplittingTags      []string `json:"splitting_tags,omitempty"`
+		IgnoreTags         []string `json:"ignore_tags,omitempty"`
+		ShowBilled         bool     `json:"show_billed_characters"`
 	}{
-		Text:       text,
-		TargetLang: to.String(),
-	}
-
-	if from != lang.AutoDetect {
-		params.SourceLang = from.String()
-	}
-
-	reqBody, err := json.Marshal(params)
+		Text:               r.Text,
+		TargetLang:         r.To.String(),
+		GlossaryID:         r.GlossaryID,
+		Formality:          string(r.Options.Formality),
+		Context:            r.Options.Context,
+		PreserveFormatting: r.Options.PreserveFormatting,
+		SplitSentences:     splitSentences[r.Options.SplitSentences],
+		ModelType:          string(r.Options.ModelType),
+		TagHandling:        string(r.Options.TagHandling),
+		OutlineDetection:   r.Options.OutlineDetection,
+		NonSplittingTags:   r.Options.NonSplittingTags,
+		SplittingTags:      r.Options.SplittingTags,
+		IgnoreTags:         r.Options.IgnoreTags,
+		ShowBilled:         true,
+	}
+
+	if r.From != lang.AutoDetect && r.From != "" {
+		params.SourceLang = r.From.String()
+	}
+
+	// markup is sent as is so the request size matches the text size
+	reqBody := &bytes.Buffer{}
+	encoder := json.NewEncoder(reqBody)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(params)
 	if err != nil {
 		return provider.Response{}, serr.New(serr.ErrInvalidRequest, "TranslateText", string(provider.DeepL), fmt.Errorf("Error json marshal: %w", err))
 	}
 
 	url := c.BaseURL.JoinPath("/translate")
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), reqBody)
 	if err != nil {
 		return provider.Response{}, serr.New(serr.ErrHTTP, "TranslateText", string(provider.DeepL), err)
 	}
@@ -281,9 +379,8 @@ func (c *DeepLClient) translateText(ctx context.Context, text []string, from lan
 	}
 	defer res.Body.Close()
 
-	ok := http.StatusOK <= res.StatusCode && res.StatusCode < http.StatusMultipleChoices // deepl /translate endpoint returns 200 or 400+ codes no in between
-	if !ok {
-		return provider.Response{}, serr.New(serr.ErrHTTP, "TranslateText", string(provider.DeepL), fmt.Errorf("response code %v , Trace ID: %v", res.StatusCode, res.Header.Get("X-Trace-ID")))
+	if err := checkResponse("TranslateText", res); err != nil {
+		return provider.Response{}, err
 	}
 
 	translations := new(Translations)
@@ -297,44 +394,81 @@ func (c *DeepLClient) translateText(ctx context.Context, text []string, from lan
 		return provider.Response{}, serr.New(serr.ErrEmptyResponse, "TranslateText", string(provider.DeepL), fmt.Errorf("Empty translation array response"))
 	}
 
-	var textlist []string
+	response := provider.Response{
+		Segments: make([]provider.SegmentMeta, 0, len(translations.Translations)),
+		Provider: provider.DeepL,
+	}
 
 	for _, trans := range translations.Translations {
-		textlist = append(textlist, trans.Text)
+		response.Text = append(response.Text, trans.Text)
+		response.Segments = append(response.Segments, provider.SegmentMeta{
+			DetectedLanguage: lang.Language(strings.ToUpper(trans.DetectedSourceLanguage)),
+			BilledCharacters: trans.BilledCharacters,
+		})
+		response.BilledCharacters += trans.BilledCharacters
+		if trans.ModelTypeUsed != "" {
+			response.Model = trans.ModelTypeUsed
+		}
 	}
 
-	return provider.Response{
-		Text: textlist,
-	}, nil
+	return response, nil
 
 }
 
-func (c *DeepLClient) translateDoc(ctx context.Context, binary []byte, filename string, from lang.Language, to lang.Language) (provider.AsyncResponse, error) {
+func (c *DeepLClient) translateDoc(ctx context.Context, r provider.Request) (provider.AsyncResponse, error) {
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	part, err := writer.CreateFormFile("file", filename)
+	part, err := writer.CreateFormFile("file", r.FileName)
 	if err != nil {
 		return provider.AsyncResponse{}, serr.New(serr.ErrInvalidRequest, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing file: %w", err))
 	}
-	_, err = io.Copy(part, bytes.NewBuffer(binary))
+	_, err = io.Copy(part, bytes.NewBuffer(r.Binary))
 	if err != nil {
 		return provider.AsyncResponse{}, serr.New(serr.ErrIO, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error copying file: %w", err))
 	}
 
-	if from != lang.AutoDetect {
-		err = writer.WriteField("source_lang", from.String())
+	if r.From != lang.AutoDetect && r.From != "" {
+		err = writer.WriteField("source_lang", r.From.String())
 		if err != nil {
 			return provider.AsyncResponse{}, serr.New(serr.ErrIO, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing source_lang to request body: %w", err))
 		}
 	}
 
-	err = writer.WriteField("target_lang", to.String())
+	err = writer.WriteField("target_lang", r.To.String())
 	if err != nil {
 		return provider.AsyncResponse{}, serr.New(serr.ErrHTTP, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing target_lang to request body: %w", err))
 	}
 
+	if r.GlossaryID != "" {
+		err = writer.WriteField("glossary_id", r.GlossaryID)
+		if err != nil {
+			return provider.AsyncResponse{}, serr.New(serr.ErrIO, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing glossary_id to request body: %w", err))
+		}
+	}
+
+	if r.Options.Formality != provider.FormalityDefault {
+		err = writer.WriteField("formality", string(r.Options.Formality))
+		if err != nil {
+			return provider.AsyncResponse{}, serr.New(serr.ErrIO, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing formality to request body: %w", err))
+		}
+	}
+
+	if r.Options.OutputFormat != "" {
+		err = writer.WriteField("output_format", strings.TrimPrefix(outputExtension(r.Options.OutputFormat), "."))
+		if err != nil {
+			return provider.AsyncResponse{}, serr.New(serr.ErrIO, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing output_format to request body: %w", err))
+		}
+	}
+
+	if r.Options.MinifyDocument {
+		err = writer.WriteField("enable_document_minification", "true")
+		if err != nil {
+			return provider.AsyncResponse{}, serr.New(serr.ErrIO, "TranslateDocument", string(provider.DeepL), fmt.Errorf("Error writing enable_document_minification to request body: %w", err))
+		}
+	}
+
 	writer.Close()
 
 	url := c.BaseURL.JoinPath("/document")
@@ -351,9 +485,8 @@ func (c *DeepLClient) translateDoc(ctx context.Context, binary []byte, filename
 	}
 	defer res.Body.Close()
 
-	ok := http.StatusOK <= res.StatusCode && res.StatusCode < http.StatusMultipleChoices
-	if !ok {
-		return provider.AsyncResponse{}, serr.New(serr.ErrHTTP, "TranslateDocument", string(provider.DeepL), fmt.Errorf("response code %v , Trace ID: %v", res.StatusCode, res.Header.Get("X-Trace-ID")))
+	if err := checkResponse("TranslateDocument", res); err != nil {
+		return provider.AsyncResponse{}, err
 	}
 
 	document := new(Documents)
@@ -366,6 +499,7 @@ func (c *DeepLClient) translateDoc(ctx context.Context, binary []byte, filename
 	return provider.AsyncResponse{
 		DocumentID:  document.DocID,
 		DocumentKey: document.DocKey,
+		FileName:    outputFileName(r),
 	}, nil
 
 }
@@ -396,9 +530,8 @@ func (c *DeepLClient) CheckStatus(ctx context.Context, obj provider.AsyncRespons
 	}
 	defer res.Body.Close()
 
-	ok := http.StatusOK <= res.StatusCode && res.StatusCode < http.StatusMultipleChoices
-	if !ok {
-		return provider.JobStatus{}, serr.New(serr.ErrHTTP, "CheckDocumentStatus", string(provider.DeepL), fmt.Errorf("response code %v , Trace ID: %v", res.StatusCode, res.Header.Get("X-Trace-ID")))
+	if err := checkResponse("CheckDocumentStatus", res); err != nil {
+		return provider.JobStatus{}, err
 	}
 
 	status := new(Status)
@@ -413,6 +546,7 @@ func (c *DeepLClient) CheckStatus(ctx context.Context, obj provider.AsyncRespons
 		Failed:           false,
 		SecondsRemaining: 0,
 		Message:          "",
+		BilledCharacters: status.BilledCharacters,
 	}
 
 	if status.Status == "error" {
@@ -470,7 +604,7 @@ func (c *DeepLClient) GetResult(ctx context.Context, obj provider.AsyncResponse)
 		if res.StatusCode == 503 {
 			return provider.Response{}, serr.New(serr.ErrProviderAPI, "GetResult", string(provider.DeepL), errors.New("Document Already downloaded"))
 		}
-		return provider.Response{}, serr.New(serr.ErrHTTP, "GetResult", string(provider.DeepL), fmt.Errorf("response code %v , Trace ID: %v", res.StatusCode, res.Header.Get("X-Trace-ID")))
+		return provider.Response{}, serr.New(serr.ErrHTTP, "GetResult", string(provider.DeepL), &serr.HTTPError{StatusCode: res.StatusCode, TraceID: res.Header.Get("X-Trace-ID")})
 	}
 
 	body, err := io.ReadAll(res.Body)
@@ -478,6 +612,54 @@ func (c *DeepLClient) GetResult(ctx context.Context, obj provider.AsyncResponse)
 		return provider.Response{}, serr.New(serr.ErrIO, "GetResult", string(provider.DeepL), fmt.Errorf("Error reading Document: %w", err))
 	}
 
-	return provider.Response{Binary: body}, nil
+	return provider.Response{Binary: body, FileName: obj.FileName, Provider: provider.DeepL}, nil
 
 } // expected for obj to contain docid and dockey
+
+func (c *DeepLClient) newRequest(ctx context.Context, method string, u *url.URL, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("DeepL-Auth-Key %s", c.APIKey))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// doJSON sends a request and decodes the json response into out, if not nil
+func (c *DeepLClient) doJSON(ctx context.Context, op string, method string, u *url.URL, body io.Reader, out any) error {
+	req, err := c.newRequest(ctx, method, u, body)
+	if err != nil {
+		return serr.New(serr.ErrInvalidRequest, op, string(provider.DeepL), fmt.Errorf("Error creating http request: %w", err))
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return serr.New(serr.ErrNetwork, op, string(provider.DeepL), err)
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse(op, res); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return serr.New(serr.ErrInvalidResponse, op, string(provider.DeepL), fmt.Errorf("Error json decoding: %w", err))
+	}
+	return nil
+}
+
+func checkResponse(op string, res *http.Response) error {
+	ok := http.StatusOK <= res.StatusCode && res.StatusCode < http.StatusMultipleChoices
+	if res.StatusCode == StatusQuotaExceeded {
+		return serr.New(serr.ErrQuotaExceeded, op, string(provider.DeepL), fmt.Errorf("quota exceeded, Trace ID: %v", res.Header.Get("X-Trace-ID")))
+	}
+	if !ok {
+		return serr.New(serr.ErrHTTP, op, string(provider.DeepL), &serr.HTTPError{StatusCode: res.StatusCode, TraceID: res.Header.Get("X-Trace-ID")})
+	}
+	return nil
+}
@@ -2,13 +2,19 @@ package deepl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 
+	serr "github.com/o0n1x/sublate-go/errors"
 	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
 )
 
 func TestTranslateText(t *testing.T) {
@@ -47,7 +53,7 @@ func TestTranslateText(t *testing.T) {
 				APIKey:  "test-key",
 			}
 
-			resp, err := client.translateText(context.Background(), []string{tc.text}, tc.from, tc.to)
+			resp, err := client.translateText(context.Background(), provider.Request{Text: []string{tc.text}, From: tc.from, To: tc.to})
 
 			if err != nil {
 				t.Fatal(err)
@@ -59,3 +65,92 @@ func TestTranslateText(t *testing.T) {
 		})
 	}
 }
+
+func TestTranslateTextMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"show_billed_characters":true`) {
+			t.Errorf("billed characters not requested: %s", body)
+		}
+		fmt.Fprint(w, `{"translations":[
+			{"text":"hallo","detected_source_language":"EN","billed_characters":5},
+			{"text":"welt","detected_source_language":"en","billed_characters":5,"model_type_used":"quality_optimized"}]}`)
+	}))
+	defer server.Close()
+
+	resp, err := testClient(server).translateText(context.Background(), provider.Request{Text: []string{"hello", "world"}, To: lang.German})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []provider.SegmentMeta{{DetectedLanguage: lang.English, BilledCharacters: 5}, {DetectedLanguage: lang.English, BilledCharacters: 5}}
+	if !reflect.DeepEqual(resp.Segments, want) {
+		t.Errorf("got %+v, want %+v", resp.Segments, want)
+	}
+	if resp.BilledCharacters != 10 || resp.Provider != provider.DeepL || resp.Model != "quality_optimized" {
+		t.Errorf("unexpected response metadata %+v", resp)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/usage":
+			fmt.Fprint(w, `{"character_count":180118,"character_limit":1250000,"document_count":3,"document_limit":10}`)
+		case "/v2/translate":
+			w.WriteHeader(StatusQuotaExceeded)
+		}
+	}))
+	defer server.Close()
+	client := testClient(server)
+
+	usage, err := client.Usage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := provider.Usage{CharacterCount: 180118, CharacterLimit: 1250000, DocumentCount: 3, DocumentLimit: 10}
+	if usage != want {
+		t.Errorf("got %+v, want %+v", usage, want)
+	}
+	if usage.RemainingCharacters() != 1069882 || usage.RemainingDocuments() != 7 {
+		t.Errorf("wrong remaining quota %d, %d", usage.RemainingCharacters(), usage.RemainingDocuments())
+	}
+
+	_, err = client.translateText(context.Background(), provider.Request{Text: []string{"hello"}, To: lang.German})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrQuotaExceeded {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestGetClientOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/usage" {
+			t.Errorf("wrong path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key key:fx" || r.Header.Get("User-Agent") != "sublate-test" || r.Header.Get("X-Gateway") != "on-prem" {
+			t.Errorf("options not applied: %v", r.Header)
+		}
+		fmt.Fprint(w, `{"character_count":1,"character_limit":10}`)
+	}))
+	defer server.Close()
+
+	client, err := provider.GetClient(provider.DeepL,
+		provider.WithAPIKey("key:fx"),
+		provider.WithBaseURL(server.URL),
+		provider.WithUserAgent("sublate-test"),
+		provider.WithHeader("X-Gateway", "on-prem"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.(provider.UsageReporter).Usage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.GetClient(provider.DeepL, provider.WithBaseURL("not a url"))
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrInvalidRequest {
+		t.Errorf("expected ErrInvalidRequest for a bad base url, got %v", err)
+	}
+}
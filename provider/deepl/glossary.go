@@ -0,0 +1,301 @@
+package deepl
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// glossary entries formats accepted by ReadGlossaryEntries
+const (
+	EntriesTSV = "tsv"
+	EntriesCSV = "csv"
+)
+
+type Glossary struct {
+	GlossaryID   string    `json:"glossary_id"`
+	Name         string    `json:"name"`
+	Ready        bool      `json:"ready"`
+	SourceLang   string    `json:"source_lang"`
+	TargetLang   string    `json:"target_lang"`
+	CreationTime time.Time `json:"creation_time"`
+	EntryCount   int       `json:"entry_count"`
+}
+
+type GlossaryEntry struct {
+	Source string
+	Target string
+}
+
+// ReadGlossaryEntries reads source/target pairs from a TSV or CSV file with one entry per line
+func ReadGlossaryEntries(r io.Reader, entriesFormat string) ([]GlossaryEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	switch entriesFormat {
+	case EntriesTSV:
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	case EntriesCSV:
+	default:
+		return nil, serr.New(serr.ErrInvalidFormat, "ReadGlossaryEntries", string(provider.DeepL), fmt.Errorf("unknown entries format %q", entriesFormat))
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, serr.New(serr.ErrInvalidFormat, "ReadGlossaryEntries", string(provider.DeepL), err)
+	}
+
+	entries := make([]GlossaryEntry, 0, len(records))
+	for _, rec := range records {
+		source, target := strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1])
+		if source == "" || target == "" {
+			return nil, serr.New(serr.ErrInvalidFormat, "ReadGlossaryEntries", string(provider.DeepL), fmt.Errorf("empty glossary entry %q", rec))
+		}
+		entries = append(entries, GlossaryEntry{Source: source, Target: target})
+	}
+	return entries, nil
+}
+
+func encodeEntries(entries []GlossaryEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(e.Source)
+		b.WriteByte('\t')
+		b.WriteString(e.Target)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (c *DeepLClient) CreateGlossary(ctx context.Context, name string, from lang.Language, to lang.Language, entries []GlossaryEntry) (Glossary, error) {
+	if len(entries) == 0 {
+		return Glossary{}, serr.New(serr.ErrInvalidRequest, "CreateGlossary", string(provider.DeepL), fmt.Errorf("no glossary entries"))
+	}
+
+	params := struct {
+		Name          string `json:"name"`
+		SourceLang    string `json:"source_lang"`
+		TargetLang    string `json:"target_lang"`
+		Entries       string `json:"entries"`
+		EntriesFormat string `json:"entries_format"`
+	}{
+		Name:          name,
+		SourceLang:    from.Base().Lower(),
+		TargetLang:    to.Base().Lower(),
+		Entries:       encodeEntries(entries),
+		EntriesFormat: EntriesTSV,
+	}
+	reqBody, err := json.Marshal(params)
+	if err != nil {
+		return Glossary{}, serr.New(serr.ErrInvalidRequest, "CreateGlossary", string(provider.DeepL), fmt.Errorf("Error json marshal: %w", err))
+	}
+
+	glossary := Glossary{}
+	err = c.doJSON(ctx, "CreateGlossary", http.MethodPost, c.BaseURL.JoinPath("glossaries"), bytes.NewReader(reqBody), &glossary)
+	return glossary, err
+}
+
+func (c *DeepLClient) ListGlossaries(ctx context.Context) ([]Glossary, error) {
+	list := struct {
+		Glossaries []Glossary `json:"glossaries"`
+	}{}
+	err := c.doJSON(ctx, "ListGlossaries", http.MethodGet, c.BaseURL.JoinPath("glossaries"), nil, &list)
+	return list.Glossaries, err
+}
+
+// GetGlossary returns the current metadata of a glossary, it isn't cached so pollers see it become ready
+func (c *DeepLClient) GetGlossary(ctx context.Context, id string) (Glossary, error) {
+	glossary := Glossary{}
+	if err := c.doJSON(ctx, "GetGlossary", http.MethodGet, c.BaseURL.JoinPath("glossaries", id), nil, &glossary); err != nil {
+		return Glossary{}, err
+	}
+	return glossary, nil
+}
+
+func (c *DeepLClient) DeleteGlossary(ctx context.Context, id string) error {
+	return c.doJSON(ctx, "DeleteGlossary", http.MethodDelete, c.BaseURL.JoinPath("glossaries", id), nil, nil)
+}
+
+func (c *DeepLClient) GlossaryEntries(ctx context.Context, id string) ([]GlossaryEntry, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.BaseURL.JoinPath("glossaries", id, "entries"), nil)
+	if err != nil {
+		return nil, serr.New(serr.ErrInvalidRequest, "GlossaryEntries", string(provider.DeepL), fmt.Errorf("Error creating http request: %w", err))
+	}
+	req.Header.Set("Accept", "text/tab-separated-values")
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return nil, serr.New(serr.ErrNetwork, "GlossaryEntries", string(provider.DeepL), err)
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse("GlossaryEntries", res); err != nil {
+		return nil, err
+	}
+	return ReadGlossaryEntries(res.Body, EntriesTSV)
+}
+
+// GlossaryDictionary is a language pair of a multilingual (v3) glossary
+type GlossaryDictionary struct {
+	SourceLang string `json:"source_lang"`
+	TargetLang string `json:"target_lang"`
+	EntryCount int    `json:"entry_count"`
+}
+
+// MultilingualGlossary is a v3 glossary holding a dictionary per language pair. v2 glossaries are listed as single dictionary glossaries
+type MultilingualGlossary struct {
+	GlossaryID   string               `json:"glossary_id"`
+	Name         string               `json:"name"`
+	Dictionaries []GlossaryDictionary `json:"dictionaries"`
+	CreationTime time.Time            `json:"creation_time"`
+}
+
+// Dictionary returns the dictionary of a language pair
+func (g MultilingualGlossary) Dictionary(from lang.Language, to lang.Language) (GlossaryDictionary, bool) {
+	for _, d := range g.Dictionaries {
+		if strings.EqualFold(d.SourceLang, from.Base().String()) && strings.EqualFold(d.TargetLang, to.Base().String()) {
+			return d, true
+		}
+	}
+	return GlossaryDictionary{}, false
+}
+
+// DictionaryEntries are the entries of one language pair, used to create and replace v3 dictionaries
+type DictionaryEntries struct {
+	From    lang.Language
+	To      lang.Language
+	Entries []GlossaryEntry
+}
+
+type dictionaryParams struct {
+	SourceLang    string `json:"source_lang"`
+	TargetLang    string `json:"target_lang"`
+	Entries       string `json:"entries"`
+	EntriesFormat string `json:"entries_format"`
+}
+
+func (d DictionaryEntries) params() dictionaryParams {
+	return dictionaryParams{
+		SourceLang:    d.From.Base().Lower(),
+		TargetLang:    d.To.Base().Lower(),
+		Entries:       encodeEntries(d.Entries),
+		EntriesFormat: EntriesTSV,
+	}
+}
+
+// v3 returns the url of a v3 endpoint, next to the v2 BaseURL
+func (c *DeepLClient) v3(elem ...string) *url.URL {
+	return c.BaseURL.JoinPath(append([]string{"..", "v3"}, elem...)...)
+}
+
+func (c *DeepLClient) CreateMultilingualGlossary(ctx context.Context, name string, dictionaries []DictionaryEntries) (MultilingualGlossary, error) {
+	params := struct {
+		Name         string             `json:"name"`
+		Dictionaries []dictionaryParams `json:"dictionaries"`
+	}{Name: name}
+	for _, d := range dictionaries {
+		if len(d.Entries) == 0 {
+			return MultilingualGlossary{}, serr.New(serr.ErrInvalidRequest, "CreateMultilingualGlossary", string(provider.DeepL), fmt.Errorf("no glossary entries for %s->%s", d.From, d.To))
+		}
+		params.Dictionaries = append(params.Dictionaries, d.params())
+	}
+	if len(params.Dictionaries) == 0 {
+		return MultilingualGlossary{}, serr.New(serr.ErrInvalidRequest, "CreateMultilingualGlossary", string(provider.DeepL), fmt.Errorf("no glossary dictionaries"))
+	}
+	reqBody, err := json.Marshal(params)
+	if err != nil {
+		return MultilingualGlossary{}, serr.New(serr.ErrInvalidRequest, "CreateMultilingualGlossary", string(provider.DeepL), fmt.Errorf("Error json marshal: %w", err))
+	}
+
+	glossary := MultilingualGlossary{}
+	err = c.doJSON(ctx, "CreateMultilingualGlossary", http.MethodPost, c.v3("glossaries"), bytes.NewReader(reqBody), &glossary)
+	return glossary, err
+}
+
+func (c *DeepLClient) ListMultilingualGlossaries(ctx context.Context) ([]MultilingualGlossary, error) {
+	list := struct {
+		Glossaries []MultilingualGlossary `json:"glossaries"`
+	}{}
+	err := c.doJSON(ctx, "ListMultilingualGlossaries", http.MethodGet, c.v3("glossaries"), nil, &list)
+	return list.Glossaries, err
+}
+
+func (c *DeepLClient) GetMultilingualGlossary(ctx context.Context, id string) (MultilingualGlossary, error) {
+	glossary := MultilingualGlossary{}
+	if err := c.doJSON(ctx, "GetMultilingualGlossary", http.MethodGet, c.v3("glossaries", id), nil, &glossary); err != nil {
+		return MultilingualGlossary{}, err
+	}
+	return glossary, nil
+}
+
+func (c *DeepLClient) DeleteMultilingualGlossary(ctx context.Context, id string) error {
+	return c.doJSON(ctx, "DeleteMultilingualGlossary", http.MethodDelete, c.v3("glossaries", id), nil, nil)
+}
+
+// ReplaceDictionary replaces the entries of a language pair of a v3 glossary, adding the pair if it is missing
+func (c *DeepLClient) ReplaceDictionary(ctx context.Context, id string, dictionary DictionaryEntries) (GlossaryDictionary, error) {
+	if len(dictionary.Entries) == 0 {
+		return GlossaryDictionary{}, serr.New(serr.ErrInvalidRequest, "ReplaceDictionary", string(provider.DeepL), fmt.Errorf("no glossary entries"))
+	}
+	reqBody, err := json.Marshal(dictionary.params())
+	if err != nil {
+		return GlossaryDictionary{}, serr.New(serr.ErrInvalidRequest, "ReplaceDictionary", string(provider.DeepL), fmt.Errorf("Error json marshal: %w", err))
+	}
+
+	result := GlossaryDictionary{}
+	err = c.doJSON(ctx, "ReplaceDictionary", http.MethodPut, c.v3("glossaries", id, "dictionaries"), bytes.NewReader(reqBody), &result)
+	return result, err
+}
+
+// DictionaryEntries returns the entries of a language pair of a v3 glossary
+func (c *DeepLClient) DictionaryEntries(ctx context.Context, id string, from lang.Language, to lang.Language) ([]GlossaryEntry, error) {
+	u := c.v3("glossaries", id, "entries")
+	u.RawQuery = url.Values{"source_lang": {from.Base().Lower()}, "target_lang": {to.Base().Lower()}}.Encode()
+
+	result := struct {
+		Dictionaries []dictionaryParams `json:"dictionaries"`
+	}{}
+	if err := c.doJSON(ctx, "DictionaryEntries", http.MethodGet, u, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Dictionaries) == 0 {
+		return nil, serr.New(serr.ErrInvalidResponse, "DictionaryEntries", string(provider.DeepL), fmt.Errorf("glossary %s has no %s->%s dictionary", id, from, to))
+	}
+	return ReadGlossaryEntries(strings.NewReader(result.Dictionaries[0].Entries), EntriesTSV)
+}
+
+// checkGlossary verifies that the glossary of the request has a dictionary for its language pair.
+// the metadata is fetched for every request so edited or deleted glossaries are never checked against stale pairs
+func (c *DeepLClient) checkGlossary(ctx context.Context, req provider.Request) error {
+	if req.GlossaryID == "" {
+		return nil
+	}
+	if req.From == lang.AutoDetect || req.From == "" {
+		return serr.New(serr.ErrInvalidLanguage, "checkGlossary", string(provider.DeepL), errors.New("source language is required when using a glossary"))
+	}
+
+	glossary, err := c.GetMultilingualGlossary(ctx, req.GlossaryID)
+	if err != nil {
+		return err
+	}
+	if _, ok := glossary.Dictionary(req.From, req.To); !ok {
+		var pairs []string
+		for _, d := range glossary.Dictionaries {
+			pairs = append(pairs, d.SourceLang+"->"+d.TargetLang)
+		}
+		return serr.New(serr.ErrInvalidLanguage, "checkGlossary", string(provider.DeepL), fmt.Errorf("glossary %s is for %s, request is %s->%s", glossary.GlossaryID, strings.Join(pairs, ", "), req.From, req.To))
+	}
+	return nil
+}
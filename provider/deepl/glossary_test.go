@@ -0,0 +1,290 @@
+package deepl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// testClient returns a client for server with a warm language cache, so tests only see the calls they make
+func testClient(server *httptest.Server) *DeepLClient {
+	u, _ := url.Parse(server.URL)
+	return &DeepLClient{
+		Client:    server.Client(),
+		BaseURL:   u.JoinPath(APIVersion),
+		APIKey:    "test-key",
+		languages: languageCache{source: SupportedFromLang, target: SupportedToLang, expires: time.Now().Add(time.Hour)},
+	}
+}
+
+func TestReadGlossaryEntries(t *testing.T) {
+	cases := map[string]struct {
+		input  string
+		format string
+		want   []GlossaryEntry
+		err    bool
+	}{
+		"tsv":          {"Sublate\tSublate\nbrand\tMarke\n", EntriesTSV, []GlossaryEntry{{"Sublate", "Sublate"}, {"brand", "Marke"}}, false},
+		"csv":          {"\"hello, world\",\"hallo, Welt\"\nfee,Gebühr\n", EntriesCSV, []GlossaryEntry{{"hello, world", "hallo, Welt"}, {"fee", "Gebühr"}}, false},
+		"missing cell": {"only source\n", EntriesTSV, nil, true},
+		"empty target": {"source,\n", EntriesCSV, nil, true},
+		"bad format":   {"a\tb", "xml", nil, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ReadGlossaryEntries(strings.NewReader(tc.input), tc.format)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGlossaryEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key test-key" {
+			t.Errorf("missing auth header")
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/glossaries":
+			var params map[string]string
+			json.NewDecoder(r.Body).Decode(&params)
+			if params["entries"] != "brand\tMarke\n" || params["entries_format"] != "tsv" || params["source_lang"] != "en" || params["target_lang"] != "de" {
+				t.Errorf("unexpected create params %v", params)
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"glossary_id":"g1","name":"brand","ready":true,"source_lang":"en","target_lang":"de","entry_count":1}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/glossaries":
+			fmt.Fprint(w, `{"glossaries":[{"glossary_id":"g1","source_lang":"en","target_lang":"de"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/glossaries/g1/entries":
+			if r.Header.Get("Accept") != "text/tab-separated-values" {
+				t.Errorf("wrong accept header %q", r.Header.Get("Accept"))
+			}
+			fmt.Fprint(w, "brand\tMarke")
+		case r.Method == http.MethodDelete && r.URL.Path == "/v2/glossaries/g1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := testClient(server)
+	ctx := context.Background()
+
+	g, err := client.CreateGlossary(ctx, "brand", lang.EnglishUS, lang.German, []GlossaryEntry{{"brand", "Marke"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.GlossaryID != "g1" || g.EntryCount != 1 {
+		t.Errorf("unexpected glossary %+v", g)
+	}
+
+	list, err := client.ListGlossaries(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("got %v, %v", list, err)
+	}
+
+	entries, err := client.GlossaryEntries(ctx, "g1")
+	if err != nil || !reflect.DeepEqual(entries, []GlossaryEntry{{"brand", "Marke"}}) {
+		t.Fatalf("got %v, %v", entries, err)
+	}
+
+	if err := client.DeleteGlossary(ctx, "g1"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetGlossary(ctx, "missing")
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrHTTP {
+		t.Errorf("expected ErrHTTP, got %v", err)
+	}
+}
+
+func TestTranslateWithGlossary(t *testing.T) {
+	cases := map[string]struct {
+		from lang.Language
+		to   lang.Language
+		code serr.ErrorCode
+		ok   bool
+	}{
+		"matching pair":  {lang.English, lang.German, 0, true},
+		"wrong target":   {lang.English, lang.French, serr.ErrInvalidLanguage, false},
+		"no source lang": {lang.AutoDetect, lang.German, serr.ErrInvalidLanguage, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v3/glossaries/g1":
+					fmt.Fprint(w, `{"glossary_id":"g1","dictionaries":[{"source_lang":"en","target_lang":"de","entry_count":1}]}`)
+				case "/v2/translate":
+					body, _ := io.ReadAll(r.Body)
+					if !strings.Contains(string(body), `"glossary_id":"g1"`) {
+						t.Errorf("glossary_id not forwarded: %s", body)
+					}
+					fmt.Fprint(w, `{"translations":[{"text":"Marke"}]}`)
+				}
+			}))
+			defer server.Close()
+
+			resp, err := testClient(server).Translate(context.Background(), provider.Request{
+				ReqType:    format.Text,
+				Text:       []string{"brand"},
+				From:       tc.from,
+				To:         tc.to,
+				GlossaryID: "g1",
+			})
+			if !tc.ok {
+				var terr *serr.TranslateError
+				if !errors.As(err, &terr) || terr.Code != tc.code {
+					t.Fatalf("expected code %d, got %v", tc.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text[0] != "Marke" {
+				t.Errorf("got %v", resp.Text)
+			}
+		})
+	}
+}
+
+func TestGetGlossaryNotCached(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"glossary_id":"g1","ready":%t}`, calls > 1)
+	}))
+	defer server.Close()
+	client := testClient(server)
+
+	for _, ready := range []bool{false, true} {
+		g, err := client.GetGlossary(context.Background(), "g1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Ready != ready {
+			t.Errorf("got ready %t, want %t", g.Ready, ready)
+		}
+	}
+}
+
+func TestMultilingualGlossaryEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/glossaries":
+			var params struct {
+				Name         string              `json:"name"`
+				Dictionaries []map[string]string `json:"dictionaries"`
+			}
+			json.NewDecoder(r.Body).Decode(&params)
+			if len(params.Dictionaries) != 2 || params.Dictionaries[1]["target_lang"] != "fr" || params.Dictionaries[1]["entries"] != "brand\tmarque\n" {
+				t.Errorf("unexpected create params %+v", params)
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"glossary_id":"g1","name":"brand","dictionaries":[{"source_lang":"en","target_lang":"de","entry_count":1},{"source_lang":"en","target_lang":"fr","entry_count":1}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/glossaries":
+			fmt.Fprint(w, `{"glossaries":[{"glossary_id":"g1","dictionaries":[{"source_lang":"en","target_lang":"de"}]}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/glossaries/g1/entries":
+			if r.URL.Query().Get("source_lang") != "en" || r.URL.Query().Get("target_lang") != "de" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"dictionaries":[{"source_lang":"en","target_lang":"de","entries":"brand\tMarke","entries_format":"tsv"}]}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/v3/glossaries/g1/dictionaries":
+			fmt.Fprint(w, `{"source_lang":"en","target_lang":"es","entry_count":1}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v3/glossaries/g1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := testClient(server)
+	ctx := context.Background()
+
+	g, err := client.CreateMultilingualGlossary(ctx, "brand", []DictionaryEntries{
+		{From: lang.English, To: lang.German, Entries: []GlossaryEntry{{"brand", "Marke"}}},
+		{From: lang.English, To: lang.French, Entries: []GlossaryEntry{{"brand", "marque"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Dictionary(lang.EnglishUS, lang.French); !ok || g.GlossaryID != "g1" {
+		t.Errorf("unexpected glossary %+v", g)
+	}
+
+	list, err := client.ListMultilingualGlossaries(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("got %v, %v", list, err)
+	}
+
+	entries, err := client.DictionaryEntries(ctx, "g1", lang.English, lang.German)
+	if err != nil || !reflect.DeepEqual(entries, []GlossaryEntry{{"brand", "Marke"}}) {
+		t.Fatalf("got %v, %v", entries, err)
+	}
+
+	d, err := client.ReplaceDictionary(ctx, "g1", DictionaryEntries{From: lang.English, To: lang.Spanish, Entries: []GlossaryEntry{{"brand", "marca"}}})
+	if err != nil || d.TargetLang != "es" {
+		t.Fatalf("got %+v, %v", d, err)
+	}
+
+	if err := client.DeleteMultilingualGlossary(ctx, "g1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.CreateMultilingualGlossary(ctx, "empty", nil); err == nil {
+		t.Error("expected an error without dictionaries")
+	}
+}
+
+func TestCheckGlossaryNotCached(t *testing.T) {
+	dictionaries := `{"source_lang":"en","target_lang":"de"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"glossary_id":"g1","dictionaries":[%s]}`, dictionaries)
+	}))
+	defer server.Close()
+	client := testClient(server)
+	ctx := context.Background()
+
+	req := provider.Request{From: lang.English, To: lang.German, GlossaryID: "g1"}
+	if err := client.checkGlossary(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	// the glossary is edited elsewhere: a dictionary is added and en->de removed
+	dictionaries = `{"source_lang":"en","target_lang":"fr"}`
+	if err := client.checkGlossary(ctx, req); err == nil {
+		t.Error("a removed dictionary should be rejected")
+	}
+	req.To = lang.French
+	if err := client.checkGlossary(ctx, req); err != nil {
+		t.Errorf("an added dictionary should be accepted, got %v", err)
+	}
+}
@@ -22,7 +22,7 @@ func TestDeeplIntegrationText(t *testing.T) {
 		t.Fatal("DEEPL_API_KEY not set")
 	}
 
-	deeplclient, err := provider.GetClient(provider.DeepL, apiKey)
+	deeplclient, err := provider.GetClient(provider.DeepL, provider.WithAPIKey(apiKey))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -61,7 +61,7 @@ func TestDeeplIntegrationFile(t *testing.T) {
 		t.Fatal("DEEPL_API_KEY not set")
 	}
 
-	generalizedclient, err := provider.GetClient(provider.DeepL, apiKey)
+	generalizedclient, err := provider.GetClient(provider.DeepL, provider.WithAPIKey(apiKey))
 	if err != nil {
 		t.Fatal(err)
 	}
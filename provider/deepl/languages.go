@@ -0,0 +1,142 @@
+package deepl
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// LanguagesTTL is how long the languages fetched from DeepL are cached
+var LanguagesTTL = 24 * time.Hour
+
+// languagesRetry is how long the static SupportedFromLang/SupportedToLang maps are used after a failed fetch
+const languagesRetry = time.Minute
+
+type languageCache struct {
+	mu      sync.Mutex
+	source  map[lang.Language]bool
+	target  map[lang.Language]bool
+	err     error // error of the last fetch, the static maps are cached meanwhile
+	expires time.Time
+	// closed once the running fetch is done, nil when there is none. the fetch runs without mu
+	fetching chan struct{}
+}
+
+// SourceLanguages returns the source languages supported by DeepL, AutoDetect excluded.
+// when DeepL can't be reached the static SupportedFromLang list is returned along with the error
+func (c *DeepLClient) SourceLanguages(ctx context.Context) ([]lang.Language, error) {
+	source, _, err := c.languageLists(ctx)
+	return sortedLanguages(source), err
+}
+
+// TargetLanguages returns the target languages supported by DeepL.
+// when DeepL can't be reached the static SupportedToLang list is returned along with the error
+func (c *DeepLClient) TargetLanguages(ctx context.Context) ([]lang.Language, error) {
+	_, target, err := c.languageLists(ctx)
+	return sortedLanguages(target), err
+}
+
+func sortedLanguages(langs map[lang.Language]bool) []lang.Language {
+	list := slices.Sorted(maps.Keys(langs))
+	return slices.DeleteFunc(list, func(l lang.Language) bool { return l == lang.AutoDetect })
+}
+
+// languageLists returns the cached languages of DeepL, refreshing them once expired.
+// concurrent callers share a single fetch. when DeepL can't be reached the static maps are returned along with the error
+func (c *DeepLClient) languageLists(ctx context.Context) (source, target map[lang.Language]bool, err error) {
+	cache := &c.languages
+	var fetching chan struct{}
+	for {
+		cache.mu.Lock()
+		if time.Now().Before(cache.expires) {
+			defer cache.mu.Unlock()
+			return cache.source, cache.target, cache.err
+		}
+		if cache.fetching == nil {
+			fetching = make(chan struct{})
+			cache.fetching = fetching
+			cache.mu.Unlock()
+			break
+		}
+		wait := cache.fetching
+		cache.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return SupportedFromLang, SupportedToLang, serr.New(serr.ErrNetwork, "Languages", string(provider.DeepL), ctx.Err())
+		}
+	}
+
+	source, err = c.fetchLanguages(ctx, "source")
+	if err == nil {
+		target, err = c.fetchLanguages(ctx, "target")
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.fetching = nil
+	defer close(fetching)
+
+	if err != nil {
+		source, target = SupportedFromLang, SupportedToLang
+		// a canceled caller doesn't make DeepL unreachable, the next caller fetches again
+		if ctx.Err() != nil {
+			return source, target, err
+		}
+	}
+	ttl := LanguagesTTL
+	if err != nil {
+		ttl = languagesRetry
+	}
+	cache.source, cache.target, cache.err = source, target, err
+	cache.expires = time.Now().Add(ttl)
+	return source, target, err
+}
+
+// cachedLanguages returns the fetched languages without refreshing them, nil if none were fetched.
+// the static maps are never used here since DeepL supports languages they don't list
+func (c *DeepLClient) cachedLanguages() (source, target map[lang.Language]bool) {
+	cache := &c.languages
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.err != nil {
+		return nil, nil
+	}
+	return cache.source, cache.target
+}
+
+func (c *DeepLClient) fetchLanguages(ctx context.Context, kind string) (map[lang.Language]bool, error) {
+	u := c.BaseURL.JoinPath("languages")
+	u.RawQuery = url.Values{"type": {kind}}.Encode()
+
+	var list []struct {
+		Language string `json:"language"`
+		Name     string `json:"name"`
+	}
+	if err := c.doJSON(ctx, "Languages", http.MethodGet, u, nil, &list); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, serr.New(serr.ErrEmptyResponse, "Languages", string(provider.DeepL), errors.New("no "+kind+" languages"))
+	}
+
+	langs := make(map[lang.Language]bool, len(list)+1)
+	for _, l := range list {
+		langs[lang.Language(strings.ToUpper(l.Language))] = true
+	}
+	if kind == "source" {
+		langs[lang.AutoDetect] = true
+	}
+	return langs, nil
+}
@@ -0,0 +1,180 @@
+package deepl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+func TestLanguages(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/languages" {
+			t.Errorf("wrong path: %s", r.URL.Path)
+		}
+		fetches++
+		switch r.URL.Query().Get("type") {
+		case "source":
+			fmt.Fprint(w, `[{"language":"EN","name":"English"},{"language":"VI","name":"Vietnamese"}]`)
+		case "target":
+			fmt.Fprint(w, `[{"language":"de","name":"German"},{"language":"VI","name":"Vietnamese"}]`)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := &DeepLClient{Client: server.Client(), BaseURL: u.JoinPath(APIVersion), APIKey: "test-key"}
+	ctx := context.Background()
+
+	source, err := client.SourceLanguages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []lang.Language{lang.English, "VI"}; !reflect.DeepEqual(source, want) {
+		t.Errorf("got %v, want %v", source, want)
+	}
+	target, err := client.TargetLanguages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []lang.Language{lang.German, "VI"}; !reflect.DeepEqual(target, want) {
+		t.Errorf("got %v, want %v", target, want)
+	}
+	if fetches != 2 {
+		t.Errorf("languages should be cached, fetched %d times", fetches)
+	}
+
+	cases := map[string]struct {
+		from lang.Language
+		to   lang.Language
+		ok   bool
+	}{
+		"new language":     {lang.English, "VI", true},
+		"auto detect":      {lang.AutoDetect, lang.German, true},
+		"dropped language": {lang.English, lang.French, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.validateRequest(ctx, provider.Request{ReqType: format.Text, Text: []string{"hello"}, From: tc.from, To: tc.to})
+			if (err == nil) != tc.ok {
+				t.Errorf("got %v, want ok %v", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestLanguagesOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := &DeepLClient{Client: server.Client(), BaseURL: u.JoinPath(APIVersion), APIKey: "test-key"}
+
+	target, err := client.TargetLanguages(context.Background())
+	if len(target) != len(SupportedToLang) {
+		t.Errorf("expected the static list when offline, got %d languages", len(target))
+	}
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrHTTP {
+		t.Errorf("expected the fetch error along with the static list, got %v", err)
+	}
+	// the error is kept while the static list is cached
+	if source, err := client.SourceLanguages(context.Background()); err == nil || len(source) == 0 {
+		t.Errorf("got %d languages, %v", len(source), err)
+	}
+	// validation falls back to the static lists
+	if _, err := client.validateRequest(context.Background(), provider.Request{ReqType: format.Text, Text: []string{"hello"}, To: lang.French}); err != nil {
+		t.Errorf("static languages not used: %v", err)
+	}
+}
+
+func TestLanguagesSingleFetch(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		fmt.Fprint(w, `[{"language":"DE","name":"German"}]`)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := &DeepLClient{Client: server.Client(), BaseURL: u.JoinPath(APIVersion), APIKey: "test-key"}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if target, _ := client.TargetLanguages(context.Background()); len(target) != 1 {
+				t.Errorf("got %v", target)
+			}
+		})
+	}
+
+	// a caller whose context ends doesn't wait for the running fetch
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.languageLists(ctx); err == nil {
+		t.Error("expected the context error while waiting")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("concurrent callers should share a fetch, got %d requests", n)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	client := GetDeeplClient("key:fx")
+	caps := client.Capabilities()
+	if len(caps.TargetLanguages) != 0 || !caps.SupportsTarget(lang.Thai) || !caps.SupportsSource(lang.AutoDetect) || !caps.SupportsFormat(format.File) {
+		t.Errorf("languages should be unrestricted before they are fetched, got %+v", caps)
+	}
+
+	client.languages = languageCache{source: map[lang.Language]bool{lang.English: true}, target: map[lang.Language]bool{"VI": true}}
+	caps = client.Capabilities()
+	if !caps.SupportsTarget("VI") || caps.SupportsTarget(lang.German) || !caps.SupportsSource(lang.English) {
+		t.Errorf("cached languages not used, got %+v", caps)
+	}
+
+	client.languages.err = errors.New("unreachable")
+	if caps := client.Capabilities(); len(caps.TargetLanguages) != 0 {
+		t.Errorf("the fallback of a failed fetch should not restrict languages, got %+v", caps)
+	}
+}
+
+func TestTranslateNewLanguage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/languages":
+			fmt.Fprint(w, `[{"language":"EN","name":"English"},{"language":"TH","name":"Thai"}]`)
+		case "/v2/translate":
+			fmt.Fprint(w, `{"translations":[{"text":"สวัสดี"}]}`)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := &DeepLClient{Client: server.Client(), BaseURL: u.JoinPath(APIVersion), APIKey: "test-key"}
+
+	req := provider.Request{ReqType: format.Text, Text: []string{"hello"}, To: lang.Thai}
+	if err := provider.CheckCapabilities(req, format.Text, client); err != nil {
+		t.Fatalf("a fresh client should not reject languages missing from the static lists: %v", err)
+	}
+	resp, err := client.Translate(context.Background(), req)
+	if err != nil || resp.Text[0] != "สวัสดี" {
+		t.Errorf("got %+v, %v", resp, err)
+	}
+}
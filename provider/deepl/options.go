@@ -0,0 +1,129 @@
+package deepl
+
+import (
+	"path/filepath"
+	"strings"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// target languages that support the more/less formality settings
+var FormalityLang = map[lang.Language]bool{
+	lang.German:             true,
+	lang.French:             true,
+	lang.Italian:            true,
+	lang.Spanish:            true,
+	lang.Dutch:              true,
+	lang.Polish:             true,
+	lang.PortugueseBrazil:   true,
+	lang.PortuguesePortugal: true,
+	lang.Japanese:           true,
+	lang.Russian:            true,
+}
+
+var splitSentences = map[provider.SentenceSplitting]string{
+	provider.SplitOff:        "0",
+	provider.SplitOn:         "1",
+	provider.SplitNoNewlines: "nonewlines",
+}
+
+func unsupported(option string, value any, reason string) *serr.TranslateError {
+	return serr.New(serr.ErrUnsupportedOption, "checkOptions", string(provider.DeepL), &provider.UnsupportedOptionError{Option: option, Value: value, Reason: reason})
+}
+
+// checkOptions rejects the options DeepL can't honor for the request
+func checkOptions(req provider.Request) *serr.TranslateError {
+	opts := req.Options
+
+	switch opts.Formality {
+	case provider.FormalityDefault, provider.FormalityPreferMore, provider.FormalityPreferLess:
+	case provider.FormalityMore, provider.FormalityLess:
+		if !FormalityLang[req.To] {
+			return unsupported("Formality", opts.Formality, "target language "+req.To.String()+" has no formality")
+		}
+	default:
+		return unsupported("Formality", opts.Formality, "")
+	}
+
+	switch opts.ModelType {
+	case provider.ModelDefault, provider.ModelQualityOptimized, provider.ModelLatencyOptimized, provider.ModelPreferQuality:
+	default:
+		return unsupported("ModelType", opts.ModelType, "")
+	}
+
+	if opts.SplitSentences != provider.SplitDefault && splitSentences[opts.SplitSentences] == "" {
+		return unsupported("SplitSentences", opts.SplitSentences, "")
+	}
+
+	switch opts.TagHandling {
+	case provider.TagHandlingNone:
+		switch {
+		case opts.OutlineDetection != nil:
+			return unsupported("OutlineDetection", *opts.OutlineDetection, "needs TagHandling")
+		case len(opts.NonSplittingTags) > 0:
+			return unsupported("NonSplittingTags", opts.NonSplittingTags, "needs TagHandling")
+		case len(opts.SplittingTags) > 0:
+			return unsupported("SplittingTags", opts.SplittingTags, "needs TagHandling")
+		case len(opts.IgnoreTags) > 0:
+			return unsupported("IgnoreTags", opts.IgnoreTags, "needs TagHandling")
+		}
+	case provider.TagHandlingXML, provider.TagHandlingHTML:
+		if opts.OutlineDetection != nil && opts.TagHandling != provider.TagHandlingXML {
+			return unsupported("OutlineDetection", *opts.OutlineDetection, "only supported for xml")
+		}
+	default:
+		return unsupported("TagHandling", opts.TagHandling, "")
+	}
+
+	if req.ReqType != format.File {
+		switch {
+		case opts.OutputFormat != "":
+			return unsupported("OutputFormat", opts.OutputFormat, "only supported for documents")
+		case opts.MinifyDocument:
+			return unsupported("MinifyDocument", true, "only supported for documents")
+		}
+	}
+
+	// the document endpoint only knows about formality, glossaries and the output file
+	if req.ReqType == format.File {
+		switch {
+		case opts.Context != "":
+			return unsupported("Context", opts.Context, "not supported for documents")
+		case opts.PreserveFormatting:
+			return unsupported("PreserveFormatting", true, "not supported for documents")
+		case opts.SplitSentences != provider.SplitDefault:
+			return unsupported("SplitSentences", opts.SplitSentences, "not supported for documents")
+		case opts.ModelType != provider.ModelDefault:
+			return unsupported("ModelType", opts.ModelType, "not supported for documents")
+		case opts.TagHandling != provider.TagHandlingNone:
+			return unsupported("TagHandling", opts.TagHandling, "not supported for documents")
+		}
+
+		if opts.OutputFormat != "" {
+			if _, ok := format.ByExtension(outputExtension(opts.OutputFormat)); !ok {
+				return unsupported("OutputFormat", opts.OutputFormat, "unknown file type")
+			}
+		}
+		// DeepL can only minify office documents
+		if ext := strings.ToLower(filepath.Ext(req.FileName)); opts.MinifyDocument && ext != ".docx" && ext != ".pptx" {
+			return unsupported("MinifyDocument", true, "only supported for docx and pptx")
+		}
+	}
+	return nil
+}
+
+// outputExtension normalizes an output format to an extension. ex: "DOCX" -> ".docx"
+func outputExtension(outputFormat string) string {
+	return "." + strings.ToLower(strings.TrimPrefix(outputFormat, "."))
+}
+
+// outputFileName returns the name of the translated document
+func outputFileName(req provider.Request) string {
+	if req.Options.OutputFormat == "" {
+		return req.FileName
+	}
+	return strings.TrimSuffix(req.FileName, filepath.Ext(req.FileName)) + outputExtension(req.Options.OutputFormat)
+}
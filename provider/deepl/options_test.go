@@ -0,0 +1,245 @@
+package deepl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+func TestTranslateOptions(t *testing.T) {
+	cases := map[string]struct {
+		to   lang.Language
+		opts provider.Options
+		want map[string]any
+	}{
+		"defaults": {lang.German, provider.Options{}, map[string]any{}},
+		"all": {lang.German, provider.Options{
+			Formality:          provider.FormalityMore,
+			Context:            "a greeting card",
+			PreserveFormatting: true,
+			SplitSentences:     provider.SplitNoNewlines,
+			ModelType:          provider.ModelQualityOptimized,
+		}, map[string]any{
+			"formality":           "more",
+			"context":             "a greeting card",
+			"preserve_formatting": true,
+			"split_sentences":     "nonewlines",
+			"model_type":          "quality_optimized",
+		}},
+		"prefer formality": {lang.Korean, provider.Options{Formality: provider.FormalityPreferLess}, map[string]any{"formality": "prefer_less"}},
+		"split off":        {lang.German, provider.Options{SplitSentences: provider.SplitOff}, map[string]any{"split_sentences": "0"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var params map[string]any
+				json.NewDecoder(r.Body).Decode(&params)
+				for _, key := range []string{"formality", "context", "preserve_formatting", "split_sentences", "model_type"} {
+					if fmt.Sprint(params[key]) != fmt.Sprint(tc.want[key]) {
+						t.Errorf("%s: got %v, want %v", key, params[key], tc.want[key])
+					}
+				}
+				fmt.Fprint(w, `{"translations":[{"text":"hallo"}]}`)
+			}))
+			defer server.Close()
+
+			_, err := testClient(server).Translate(context.Background(), provider.Request{
+				ReqType: format.Text,
+				Text:    []string{"hello"},
+				To:      tc.to,
+				Options: tc.opts,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUnsupportedOptions(t *testing.T) {
+	cases := map[string]struct {
+		reqType format.Format
+		to      lang.Language
+		opts    provider.Options
+		option  string
+	}{
+		"formality without support": {format.Text, lang.Korean, provider.Options{Formality: provider.FormalityLess}, "Formality"},
+		"unknown formality":         {format.Text, lang.German, provider.Options{Formality: "casual"}, "Formality"},
+		"unknown model":             {format.Text, lang.German, provider.Options{ModelType: "fast"}, "ModelType"},
+		"document context":          {format.File, lang.German, provider.Options{Context: "invoice"}, "Context"},
+		"document model":            {format.File, lang.German, provider.Options{ModelType: provider.ModelLatencyOptimized}, "ModelType"},
+		"tags without handling":     {format.Text, lang.German, provider.Options{IgnoreTags: []string{"code"}}, "IgnoreTags"},
+		"html outline detection":    {format.Text, lang.German, provider.Options{TagHandling: provider.TagHandlingHTML, OutlineDetection: ptr(false)}, "OutlineDetection"},
+		"unknown tag handling":      {format.Text, lang.German, provider.Options{TagHandling: "markdown"}, "TagHandling"},
+		"document tag handling":     {format.File, lang.German, provider.Options{TagHandling: provider.TagHandlingXML}, "TagHandling"},
+		"text output format":        {format.Text, lang.German, provider.Options{OutputFormat: "docx"}, "OutputFormat"},
+		"unknown output format":     {format.File, lang.German, provider.Options{OutputFormat: "exe"}, "OutputFormat"},
+		"minify text file":          {format.File, lang.German, provider.Options{MinifyDocument: true}, "MinifyDocument"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request to %s", r.URL.Path)
+			}))
+			defer server.Close()
+
+			client := testClient(server)
+			req := provider.Request{ReqType: tc.reqType, Text: []string{"hello"}, FileName: "a.txt", Binary: []byte("hello"), To: tc.to, Options: tc.opts}
+			var err error
+			if tc.reqType == format.File {
+				_, err = client.AsyncTranslate(context.Background(), req)
+			} else {
+				_, err = client.Translate(context.Background(), req)
+			}
+
+			var terr *serr.TranslateError
+			if !errors.As(err, &terr) || terr.Code != serr.ErrUnsupportedOption {
+				t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+			}
+			var oerr *provider.UnsupportedOptionError
+			if !errors.As(err, &oerr) || oerr.Option != tc.option {
+				t.Errorf("expected option %s, got %v", tc.option, err)
+			}
+		})
+	}
+}
+
+func TestTagHandling(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		opts provider.Options
+		want string
+	}{
+		"xml": {
+			`<p>Hello <x id="1"/> world</p><code>Hello</code>`,
+			provider.Options{
+				TagHandling:      provider.TagHandlingXML,
+				OutlineDetection: ptr(false),
+				NonSplittingTags: []string{"x"},
+				SplittingTags:    []string{"p"},
+				IgnoreTags:       []string{"code"},
+			},
+			`<p>Hallo <x id="1"/> Welt</p><code>Hello</code>`,
+		},
+		"html": {
+			`<h1>Hello</h1><p class="intro">Hello <b>world</b></p>`,
+			provider.Options{TagHandling: provider.TagHandlingHTML},
+			`<h1>Hallo</h1><p class="intro">Hallo <b>Welt</b></p>`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var params struct {
+					Text             []string `json:"text"`
+					TagHandling      string   `json:"tag_handling"`
+					OutlineDetection *bool    `json:"outline_detection"`
+					NonSplittingTags []string `json:"non_splitting_tags"`
+					SplittingTags    []string `json:"splitting_tags"`
+					IgnoreTags       []string `json:"ignore_tags"`
+				}
+				json.NewDecoder(r.Body).Decode(&params)
+
+				if params.TagHandling != string(tc.opts.TagHandling) {
+					t.Errorf("got tag_handling %q, want %q", params.TagHandling, tc.opts.TagHandling)
+				}
+				if !reflect.DeepEqual(params.OutlineDetection, tc.opts.OutlineDetection) {
+					t.Errorf("got outline_detection %v, want %v", params.OutlineDetection, tc.opts.OutlineDetection)
+				}
+				if !reflect.DeepEqual(params.NonSplittingTags, tc.opts.NonSplittingTags) || !reflect.DeepEqual(params.SplittingTags, tc.opts.SplittingTags) || !reflect.DeepEqual(params.IgnoreTags, tc.opts.IgnoreTags) {
+					t.Errorf("tag lists not forwarded: %+v", params)
+				}
+
+				// translate the text between tags, like DeepL does with tag handling on
+				var out string
+				if len(params.IgnoreTags) > 0 {
+					out = strings.Replace(params.Text[0], "Hello", "Hallo", 1)
+				} else {
+					out = strings.ReplaceAll(params.Text[0], "Hello", "Hallo")
+				}
+				out = strings.ReplaceAll(out, "world", "Welt")
+				json.NewEncoder(w).Encode(map[string]any{"translations": []map[string]string{{"text": out}}})
+			}))
+			defer server.Close()
+
+			resp, err := testClient(server).translateText(context.Background(), provider.Request{Text: []string{tc.text}, To: lang.German, Options: tc.opts})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text[0] != tc.want {
+				t.Errorf("got %s, want %s", resp.Text[0], tc.want)
+			}
+		})
+	}
+}
+
+func TestDocumentOutputFormat(t *testing.T) {
+	cases := map[string]struct {
+		fileName string
+		opts     provider.Options
+		fields   map[string]string
+		want     string
+	}{
+		"keep format": {"report.pdf", provider.Options{}, map[string]string{"output_format": "", "enable_document_minification": ""}, "report.pdf"},
+		"convert":     {"report.pdf", provider.Options{OutputFormat: "DOCX"}, map[string]string{"output_format": "docx"}, "report.docx"},
+		"minify":      {"slides.pptx", provider.Options{MinifyDocument: true}, map[string]string{"enable_document_minification": "true"}, "slides.pptx"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v2/document":
+					if err := r.ParseMultipartForm(1 << 20); err != nil {
+						t.Error(err)
+						return
+					}
+					for field, want := range tc.fields {
+						if got := r.FormValue(field); got != want {
+							t.Errorf("%s: got %q, want %q", field, got, want)
+						}
+					}
+					fmt.Fprint(w, `{"document_id":"d1","document_key":"k1"}`)
+				case "/v2/document/d1/result":
+					fmt.Fprint(w, "translated")
+				}
+			}))
+			defer server.Close()
+			client := testClient(server)
+
+			doc, err := client.AsyncTranslate(context.Background(), provider.Request{
+				ReqType:  format.File,
+				Binary:   []byte("document"),
+				FileName: tc.fileName,
+				To:       lang.German,
+				Options:  tc.opts,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.GetResult(context.Background(), doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.FileName != tc.want || resp.FileName != tc.want {
+				t.Errorf("got %q/%q, want %q", doc.FileName, resp.FileName, tc.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,26 @@
+package deepl
+
+import (
+	"context"
+	"net/http"
+
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// StatusQuotaExceeded is returned by DeepL once the character limit is reached
+const StatusQuotaExceeded = 456
+
+type usageResponse struct {
+	CharacterCount int64 `json:"character_count"`
+	CharacterLimit int64 `json:"character_limit"`
+	DocumentCount  int64 `json:"document_count"`
+	DocumentLimit  int64 `json:"document_limit"`
+}
+
+func (c *DeepLClient) Usage(ctx context.Context) (provider.Usage, error) {
+	usage := usageResponse{}
+	if err := c.doJSON(ctx, "Usage", http.MethodGet, c.BaseURL.JoinPath("usage"), nil, &usage); err != nil {
+		return provider.Usage{}, err
+	}
+	return provider.Usage(usage), nil
+}
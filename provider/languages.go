@@ -0,0 +1,13 @@
+package provider
+
+import (
+	"context"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// LanguageLister is implemented by clients that can ask the provider which languages it supports, use As to find it under wrappers
+type LanguageLister interface {
+	SourceLanguages(context.Context) ([]lang.Language, error)
+	TargetLanguages(context.Context) ([]lang.Language, error)
+}
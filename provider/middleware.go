@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+)
+
+// Middleware adds behavior around the text translations of a client without changing the provider. ex: logging, retries, metrics
+type Middleware func(SyncClient) SyncClient
+
+// AsyncMiddleware is the Middleware of document translations
+type AsyncMiddleware func(AsyncClient) AsyncClient
+
+// Chain returns the middleware applying mws in order, the first one is the outermost
+func Chain(mws ...Middleware) Middleware {
+	return func(c SyncClient) SyncClient {
+		for i := len(mws) - 1; i >= 0; i-- {
+			c = mws[i](c)
+		}
+		return c
+	}
+}
+
+func ChainAsync(mws ...AsyncMiddleware) AsyncMiddleware {
+	return func(c AsyncClient) AsyncClient {
+		for i := len(mws) - 1; i >= 0; i-- {
+			c = mws[i](c)
+		}
+		return c
+	}
+}
+
+// Wrap applies sync to the text side of client and async to its document side, nil middlewares are skipped.
+// the result implements the same client interfaces as client. ex:
+//
+//	client = provider.Wrap(deeplClient,
+//		provider.Chain(provider.Recovery(), provider.Logging(logger), provider.Validation()),
+//		provider.ChainAsync(provider.RecoveryAsync(), provider.LoggingAsync(logger)),
+//	)
+func Wrap(client Client, sync Middleware, async AsyncMiddleware) Client {
+	syncC, isSync := client.(SyncClient)
+	asyncC, isAsync := client.(AsyncClient)
+	if isSync && sync != nil {
+		syncC = sync(syncC)
+	}
+	if isAsync && async != nil {
+		asyncC = async(asyncC)
+	}
+
+	switch {
+	case isSync && isAsync:
+		return &wrappedClient{SyncClient: syncC, async: asyncC}
+	case isSync:
+		return syncC
+	case isAsync:
+		return asyncC
+	}
+	return client
+}
+
+// wrappedClient joins the wrapped sides of a client implementing both interfaces
+type wrappedClient struct {
+	SyncClient
+	async AsyncClient
+}
+
+// Unwrap follows the sync side, the async side wraps the same client
+func (c *wrappedClient) Unwrap() Client { return c.SyncClient }
+
+func (c *wrappedClient) AsyncTranslate(ctx context.Context, req Request) (AsyncResponse, error) {
+	return c.async.AsyncTranslate(ctx, req)
+}
+
+func (c *wrappedClient) CheckStatus(ctx context.Context, obj AsyncResponse) (JobStatus, error) {
+	return c.async.CheckStatus(ctx, obj)
+}
+
+func (c *wrappedClient) GetResult(ctx context.Context, obj AsyncResponse) (Response, error) {
+	return c.async.GetResult(ctx, obj)
+}
+
+// Unwrapper is implemented by the clients wrapping another one, ex: the clients returned by middlewares
+type Unwrapper interface {
+	Unwrap() Client
+}
+
+// As returns the first client implementing T in the chain made of client and the clients it wraps.
+// ex: provider.As[provider.UsageReporter](client) finds the reporter under the middlewares of client
+func As[T any](client Client) (T, bool) {
+	for client != nil {
+		if t, ok := client.(T); ok {
+			return t, true
+		}
+		u, ok := client.(Unwrapper)
+		if !ok {
+			break
+		}
+		client = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// hook is called after each call of an observed client, req is nil for CheckStatus and GetResult
+type hook func(ctx context.Context, op string, client Client, req *Request, elapsed time.Duration, err error)
+
+type observedClient struct {
+	SyncClient
+	hook hook
+}
+
+func (c observedClient) Unwrap() Client { return c.SyncClient }
+
+func (c observedClient) Translate(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+	res, err := c.SyncClient.Translate(ctx, req)
+	c.hook(ctx, "Translate", c.SyncClient, &req, time.Since(start), err)
+	return res, err
+}
+
+type observedAsyncClient struct {
+	AsyncClient
+	hook hook
+}
+
+func (c observedAsyncClient) Unwrap() Client { return c.AsyncClient }
+
+func (c observedAsyncClient) AsyncTranslate(ctx context.Context, req Request) (AsyncResponse, error) {
+	start := time.Now()
+	res, err := c.AsyncClient.AsyncTranslate(ctx, req)
+	c.hook(ctx, "AsyncTranslate", c.AsyncClient, &req, time.Since(start), err)
+	return res, err
+}
+
+func (c observedAsyncClient) CheckStatus(ctx context.Context, obj AsyncResponse) (JobStatus, error) {
+	start := time.Now()
+	res, err := c.AsyncClient.CheckStatus(ctx, obj)
+	c.hook(ctx, "CheckStatus", c.AsyncClient, nil, time.Since(start), err)
+	return res, err
+}
+
+func (c observedAsyncClient) GetResult(ctx context.Context, obj AsyncResponse) (Response, error) {
+	start := time.Now()
+	res, err := c.AsyncClient.GetResult(ctx, obj)
+	c.hook(ctx, "GetResult", c.AsyncClient, nil, time.Since(start), err)
+	return res, err
+}
+
+// Observer receives the duration and result of each call, op is the name of the method. ex: Translate, CheckStatus
+type Observer func(op string, provider Provider, elapsed time.Duration, err error)
+
+// Timing reports the duration of each call to observe, ex: to feed metrics
+func Timing(observe Observer) Middleware {
+	return func(next SyncClient) SyncClient {
+		return observedClient{next, timingHook(observe)}
+	}
+}
+
+func TimingAsync(observe Observer) AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return observedAsyncClient{next, timingHook(observe)}
+	}
+}
+
+func timingHook(observe Observer) hook {
+	return func(ctx context.Context, op string, client Client, req *Request, elapsed time.Duration, err error) {
+		observe(op, client.Name(), elapsed, err)
+	}
+}
+
+// Logging logs each call with its languages, size and duration. failed calls are logged as errors, nil logs to slog.Default()
+func Logging(logger *slog.Logger) Middleware {
+	return func(next SyncClient) SyncClient {
+		return observedClient{next, loggingHook(logger)}
+	}
+}
+
+func LoggingAsync(logger *slog.Logger) AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return observedAsyncClient{next, loggingHook(logger)}
+	}
+}
+
+func loggingHook(logger *slog.Logger) hook {
+	return func(ctx context.Context, op string, client Client, req *Request, elapsed time.Duration, err error) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		attrs := []slog.Attr{slog.String("provider", string(client.Name())), slog.Duration("elapsed", elapsed)}
+		if req != nil {
+			attrs = append(attrs, slog.String("from", req.From.String()), slog.String("to", req.To.String()))
+			if len(req.Binary) > 0 {
+				attrs = append(attrs, slog.String("file", req.FileName), slog.Int("bytes", len(req.Binary)))
+			} else {
+				attrs = append(attrs, slog.Int("texts", len(req.Text)))
+			}
+		}
+		if err != nil {
+			l.LogAttrs(ctx, slog.LevelError, op, append(attrs, slog.Any("error", err))...)
+			return
+		}
+		l.LogAttrs(ctx, slog.LevelInfo, op, attrs...)
+	}
+}
+
+// Validation rejects requests the client can't serve before they are sent: missing text or target language,
+// unsupported capabilities (see CheckCapabilities) and text requests over the batch limits
+func Validation() Middleware {
+	return func(next SyncClient) SyncClient {
+		return validatingClient{next}
+	}
+}
+
+func ValidationAsync() AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return validatingAsyncClient{next}
+	}
+}
+
+type validatingClient struct {
+	SyncClient
+}
+
+func (c validatingClient) Unwrap() Client { return c.SyncClient }
+
+func (c validatingClient) Translate(ctx context.Context, req Request) (Response, error) {
+	name := string(c.Name())
+	caps := c.Capabilities()
+	size := 0
+	for _, t := range req.Text {
+		size += len(t)
+	}
+
+	switch {
+	case len(req.Text) == 0:
+		return Response{}, serr.New(serr.ErrInvalidRequest, "Translate", name, fmt.Errorf("no text to translate"))
+	case req.To == "":
+		return Response{}, serr.New(serr.ErrInvalidLanguage, "Translate", name, fmt.Errorf("missing target language"))
+	case caps.MaxBatchSize > 0 && len(req.Text) > caps.MaxBatchSize:
+		return Response{}, serr.New(serr.ErrInvalidRequest, "Translate", name, fmt.Errorf("%d texts, the limit is %d", len(req.Text), caps.MaxBatchSize))
+	case caps.MaxRequestSize > 0 && size > caps.MaxRequestSize:
+		return Response{}, serr.New(serr.ErrInvalidRequest, "Translate", name, fmt.Errorf("texts are %d bytes, the limit is %d", size, caps.MaxRequestSize))
+	}
+	if err := CheckCapabilities(req, format.Text, c); err != nil {
+		return Response{}, err
+	}
+	return c.SyncClient.Translate(ctx, req)
+}
+
+type validatingAsyncClient struct {
+	AsyncClient
+}
+
+func (c validatingAsyncClient) Unwrap() Client { return c.AsyncClient }
+
+func (c validatingAsyncClient) AsyncTranslate(ctx context.Context, req Request) (AsyncResponse, error) {
+	name := string(c.Name())
+	switch {
+	case len(req.Binary) == 0:
+		return AsyncResponse{}, serr.New(serr.ErrInvalidRequest, "AsyncTranslate", name, fmt.Errorf("no document to translate"))
+	case req.To == "":
+		return AsyncResponse{}, serr.New(serr.ErrInvalidLanguage, "AsyncTranslate", name, fmt.Errorf("missing target language"))
+	}
+	if err := CheckCapabilities(req, format.File, c); err != nil {
+		return AsyncResponse{}, err
+	}
+	return c.AsyncClient.AsyncTranslate(ctx, req)
+}
+
+// Recovery turns panics of the client into ErrSystem errors instead of crashing the program
+func Recovery() Middleware {
+	return func(next SyncClient) SyncClient {
+		return recoveringClient{next}
+	}
+}
+
+func RecoveryAsync() AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return recoveringAsyncClient{next}
+	}
+}
+
+func recovered(op string, client Client, err *error) {
+	if r := recover(); r != nil {
+		*err = serr.New(serr.ErrSystem, op, string(client.Name()), fmt.Errorf("panic: %v", r))
+	}
+}
+
+type recoveringClient struct {
+	SyncClient
+}
+
+func (c recoveringClient) Unwrap() Client { return c.SyncClient }
+
+func (c recoveringClient) Translate(ctx context.Context, req Request) (res Response, err error) {
+	defer recovered("Translate", c.SyncClient, &err)
+	return c.SyncClient.Translate(ctx, req)
+}
+
+type recoveringAsyncClient struct {
+	AsyncClient
+}
+
+func (c recoveringAsyncClient) Unwrap() Client { return c.AsyncClient }
+
+func (c recoveringAsyncClient) AsyncTranslate(ctx context.Context, req Request) (res AsyncResponse, err error) {
+	defer recovered("AsyncTranslate", c.AsyncClient, &err)
+	return c.AsyncClient.AsyncTranslate(ctx, req)
+}
+
+func (c recoveringAsyncClient) CheckStatus(ctx context.Context, obj AsyncResponse) (res JobStatus, err error) {
+	defer recovered("CheckStatus", c.AsyncClient, &err)
+	return c.AsyncClient.CheckStatus(ctx, obj)
+}
+
+func (c recoveringAsyncClient) GetResult(ctx context.Context, obj AsyncResponse) (res Response, err error) {
+	defer recovered("GetResult", c.AsyncClient, &err)
+	return c.AsyncClient.GetResult(ctx, obj)
+}
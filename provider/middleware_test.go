@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// echoClient is a SyncClient and AsyncClient returning the texts, or panicking when told to
+type echoClient struct {
+	stubClient
+	caps  Capabilities
+	panic bool
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *echoClient) record(op string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, op)
+}
+
+func (c *echoClient) Capabilities() Capabilities { return c.caps }
+
+func (c *echoClient) Translate(ctx context.Context, req Request) (Response, error) {
+	c.record("Translate")
+	if c.panic {
+		panic("boom")
+	}
+	return Response{Text: req.Text}, nil
+}
+
+func (c *echoClient) AsyncTranslate(ctx context.Context, req Request) (AsyncResponse, error) {
+	c.record("AsyncTranslate")
+	if c.panic {
+		panic("boom")
+	}
+	return AsyncResponse{DocumentID: "doc"}, nil
+}
+
+func (c *echoClient) CheckStatus(ctx context.Context, obj AsyncResponse) (JobStatus, error) {
+	return JobStatus{Done: true}, nil
+}
+
+func (c *echoClient) GetResult(ctx context.Context, obj AsyncResponse) (Response, error) {
+	return Response{Binary: []byte("done")}, nil
+}
+
+// tracing records the order middlewares are called in
+func tracing(name string, trace *[]string) Middleware {
+	return func(next SyncClient) SyncClient {
+		return observedClient{next, func(context.Context, string, Client, *Request, time.Duration, error) {
+			*trace = append(*trace, name)
+		}}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var trace []string
+	client := Chain(tracing("outer", &trace), tracing("inner", &trace))(&echoClient{})
+	if _, err := client.Translate(context.Background(), Request{Text: []string{"hi"}, To: lang.German}); err != nil {
+		t.Fatal(err)
+	}
+	// hooks run after the call, the innermost first
+	if want := []string{"inner", "outer"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("got %v, want %v", trace, want)
+	}
+}
+
+func TestWrapKeepsInterfaces(t *testing.T) {
+	var ops []string
+	observe := func(op string, p Provider, elapsed time.Duration, err error) { ops = append(ops, op) }
+	client := Wrap(&echoClient{}, Timing(observe), TimingAsync(observe))
+
+	syncC, isSync := client.(SyncClient)
+	asyncC, isAsync := client.(AsyncClient)
+	if !isSync || !isAsync {
+		t.Fatalf("wrapped client lost an interface: sync %v, async %v", isSync, isAsync)
+	}
+	ctx := context.Background()
+	syncC.Translate(ctx, Request{Text: []string{"hi"}})
+	res, _ := asyncC.AsyncTranslate(ctx, Request{Binary: []byte("doc")})
+	asyncC.CheckStatus(ctx, res)
+	asyncC.GetResult(ctx, res)
+	if want := []string{"Translate", "AsyncTranslate", "CheckStatus", "GetResult"}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("got %v, want %v", ops, want)
+	}
+
+	if _, ok := Wrap(&stubClient{}, Timing(observe), nil).(SyncClient); ok {
+		t.Error("a client that isn't a SyncClient should not become one")
+	}
+}
+
+// usageClient is an echoClient reporting its usage
+type usageClient struct {
+	echoClient
+}
+
+func (c *usageClient) Usage(context.Context) (Usage, error) { return Usage{CharacterCount: 42}, nil }
+
+func TestAs(t *testing.T) {
+	inner := &usageClient{}
+	b := NewBreaker(5, time.Minute)
+	client := Wrap(inner,
+		Chain(Recovery(), Validation(), Retrying(Retry{MaxAttempts: 2}), CircuitBreaking(b), RateLimiting(NewLimiter(RateLimit{}))),
+		ChainAsync(RecoveryAsync(), RetryingAsync(Retry{MaxAttempts: 2}), CircuitBreakingAsync(b)),
+	)
+	if _, ok := client.(UsageReporter); ok {
+		t.Fatal("the wrapped client should hide the reporter")
+	}
+
+	reporter, ok := As[UsageReporter](client)
+	if !ok || reporter != inner {
+		t.Fatalf("got %v, %v, want the wrapped client", reporter, ok)
+	}
+	if _, ok := As[LanguageLister](client); ok {
+		t.Error("no client of the chain lists languages")
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	client := Chain(Logging(logger), Recovery())(&echoClient{panic: true})
+
+	client.Translate(context.Background(), Request{Text: []string{"a", "b"}, From: lang.English, To: lang.German})
+	for _, s := range []string{"level=ERROR", "msg=Translate", "provider=stub", "from=EN", "to=DE", "texts=2", "panic: boom"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("log is missing %q: %s", s, buf.String())
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	caps := Capabilities{TargetLanguages: []lang.Language{lang.German}, MaxBatchSize: 2, MaxRequestSize: 10}
+
+	cases := map[string]struct {
+		req  Request
+		code serr.ErrorCode
+	}{
+		"valid":              {Request{Text: []string{"hi"}, To: lang.German}, 0},
+		"no text":            {Request{To: lang.German}, serr.ErrInvalidRequest},
+		"no target":          {Request{Text: []string{"hi"}}, serr.ErrInvalidLanguage},
+		"unsupported target": {Request{Text: []string{"hi"}, To: lang.French}, serr.ErrInvalidLanguage},
+		"too many texts":     {Request{Text: []string{"a", "b", "c"}, To: lang.German}, serr.ErrInvalidRequest},
+		"too large":          {Request{Text: []string{"hello world"}, To: lang.German}, serr.ErrInvalidRequest},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			inner := &echoClient{caps: caps}
+			_, err := Validation()(inner).Translate(context.Background(), tc.req)
+			if tc.code == 0 {
+				if err != nil || len(inner.calls) != 1 {
+					t.Fatalf("valid request not sent: %v", err)
+				}
+				return
+			}
+			var terr *serr.TranslateError
+			if !errors.As(err, &terr) || terr.Code != tc.code {
+				t.Fatalf("expected code %d, got %v", tc.code, err)
+			}
+			if len(inner.calls) != 0 {
+				t.Error("invalid request reached the client")
+			}
+		})
+	}
+
+	_, err := ValidationAsync()(&echoClient{caps: caps}).AsyncTranslate(context.Background(), Request{To: lang.German})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrInvalidRequest {
+		t.Errorf("expected ErrInvalidRequest for a request without document, got %v", err)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	ctx := context.Background()
+	_, err := Recovery()(&echoClient{panic: true}).Translate(ctx, Request{Text: []string{"hi"}})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrSystem {
+		t.Errorf("expected ErrSystem, got %v", err)
+	}
+
+	_, err = RecoveryAsync()(&echoClient{panic: true}).AsyncTranslate(ctx, Request{Binary: []byte("doc")})
+	if !errors.As(err, &terr) || terr.Code != serr.ErrSystem {
+		t.Errorf("expected ErrSystem, got %v", err)
+	}
+}
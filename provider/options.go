@@ -0,0 +1,75 @@
+package provider
+
+import "fmt"
+
+type Formality string
+
+const (
+	FormalityDefault    Formality = ""
+	FormalityMore       Formality = "more"
+	FormalityLess       Formality = "less"
+	FormalityPreferMore Formality = "prefer_more" // falls back to default when the target language has no formality
+	FormalityPreferLess Formality = "prefer_less"
+)
+
+type SentenceSplitting int
+
+const (
+	SplitDefault    SentenceSplitting = iota // let the provider decide
+	SplitOff                                 // every text is translated as a single sentence
+	SplitOn                                  // split on punctuation and newlines
+	SplitNoNewlines                          // split on punctuation only
+)
+
+type ModelType string
+
+const (
+	ModelDefault          ModelType = ""
+	ModelQualityOptimized ModelType = "quality_optimized"
+	ModelLatencyOptimized ModelType = "latency_optimized"
+	ModelPreferQuality    ModelType = "prefer_quality_optimized" // quality model if available for the language pair
+)
+
+type TagHandling string
+
+const (
+	TagHandlingNone TagHandling = "" // markup is translated as plain text
+	TagHandlingXML  TagHandling = "xml"
+	TagHandlingHTML TagHandling = "html"
+)
+
+// Options are provider neutral translation settings. the zero value keeps the provider defaults.
+// providers that can't honor a set option return an UnsupportedOptionError instead of ignoring it
+type Options struct {
+	Formality Formality
+	// Context is extra text that influences the translation but is not translated itself
+	Context            string
+	PreserveFormatting bool
+	SplitSentences     SentenceSplitting
+	ModelType          ModelType
+
+	// markup handling of text requests, the tag lists need TagHandling to be set
+	TagHandling      TagHandling
+	OutlineDetection *bool    // nil keeps the provider default
+	NonSplittingTags []string // tags that never split sentences
+	SplittingTags    []string // tags that always split sentences
+	IgnoreTags       []string // tags whose content is not translated
+
+	// document requests only
+	OutputFormat   string // extension of the translated document when converting, ex: "docx" for a pdf. "" keeps the input format
+	MinifyDocument bool   // shrink large documents (embedded media...) before translating them
+}
+
+// UnsupportedOptionError is wrapped in an ErrUnsupportedOption TranslateError
+type UnsupportedOptionError struct {
+	Option string
+	Value  any
+	Reason string
+}
+
+func (e *UnsupportedOptionError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("unsupported option %s=%v", e.Option, e.Value)
+	}
+	return fmt.Sprintf("unsupported option %s=%v: %s", e.Option, e.Value, e.Reason)
+}
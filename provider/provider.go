@@ -2,9 +2,7 @@ package provider
 
 import (
 	"context"
-	"fmt"
 
-	serr "github.com/o0n1x/sublate-go/errors"
 	format "github.com/o0n1x/sublate-go/format"
 	lang "github.com/o0n1x/sublate-go/lang"
 )
@@ -19,15 +17,30 @@ import (
 
 type Response struct {
 	// ResType ResponseType
-	Text   []string // if sync then translation is in text field
-	Binary []byte   // if file then translation in the binary field
+	Text     []string // if sync then translation is in text field
+	Binary   []byte   // if file then translation in the binary field
+	FileName string   // name of the translated file, its extension follows Options.OutputFormat
+
+	// Segments holds metadata of each translated text in the same order as Text.
+	// for files translated locally it follows the extracted segments. nil if the provider doesn't report it
+	Segments         []SegmentMeta
+	BilledCharacters int // as reported by the provider, 0 if unknown. use GetCost for estimates
+	Provider         Provider
+	Model            string // model that translated the request, "" if the provider doesn't say
+}
 
+type SegmentMeta struct {
+	DetectedLanguage lang.Language // source language detected by the provider, "" if unknown
+	BilledCharacters int
+	MatchScore       float64 // similarity of the translation memory match the segment comes from (1 for exact), 0 if translated by the provider
 }
 
 type AsyncResponse struct {
 	//DeepL Document translation Fields
 	DocumentID  string
 	DocumentKey string
+
+	FileName string // name of the translated document once done
 }
 
 type JobStatus struct {
@@ -35,6 +48,7 @@ type JobStatus struct {
 	Failed           bool
 	SecondsRemaining int
 	Message          string
+	BilledCharacters int // set once the job is done, if the provider reports it
 }
 
 type Request struct {
@@ -44,15 +58,13 @@ type Request struct {
 	FileName string   // is used when format is File
 	From     lang.Language
 	To       lang.Language
+	// GlossaryID references a glossary created with the provider. the glossary language pair must match From and To
+	GlossaryID string
+	Options    Options
 }
 
-type ClientFactory func(apiKey string) Client
-
-var registry = map[Provider]ClientFactory{}
-
-func Register(name Provider, factory ClientFactory) {
-	registry[name] = factory
-}
+// ClientFactory builds a client from the config. it should honor every Config field the same way other providers do
+type ClientFactory func(Config) (Client, error)
 
 type Provider string
 
@@ -64,6 +76,7 @@ const (
 type Client interface {
 	GetCost(Request) float32
 	GetCharCount(Request) int
+	Capabilities() Capabilities
 	Name() Provider
 	Version() string
 }
@@ -79,11 +92,3 @@ type AsyncClient interface {
 	GetResult(context.Context, AsyncResponse) (Response, error)
 	Client
 }
-
-func GetClient(name Provider, apiKey string) (Client, error) {
-	factory, ok := registry[name]
-	if !ok {
-		return nil, serr.New(serr.ErrInvalidProvider, "GetClient", "", fmt.Errorf("%s is not a valid provider", name))
-	}
-	return factory(apiKey), nil
-}
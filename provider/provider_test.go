@@ -1 +1,164 @@
 package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// stubClient records the config it was built with
+type stubClient struct {
+	cfg Config
+}
+
+func (c *stubClient) GetCost(Request) float32    { return 0 }
+func (c *stubClient) GetCharCount(Request) int   { return 0 }
+func (c *stubClient) Capabilities() Capabilities { return Capabilities{} }
+func (c *stubClient) Name() Provider             { return "stub" }
+func (c *stubClient) Version() string            { return "test" }
+func stubFactory(cfg Config) (Client, error)     { return &stubClient{cfg: cfg}, nil }
+
+func TestConfigHTTPClient(t *testing.T) {
+	custom := &http.Client{Timeout: 5 * time.Second}
+
+	cases := map[string]struct {
+		opts    []ClientOption
+		timeout time.Duration
+		agent   string
+		header  string
+	}{
+		"defaults":           {nil, DefaultTimeout, "Go-http-client/1.1", ""},
+		"timeout":            {[]ClientOption{WithTimeout(time.Second)}, time.Second, "Go-http-client/1.1", ""},
+		"custom client":      {[]ClientOption{WithHTTPClient(custom)}, 5 * time.Second, "Go-http-client/1.1", ""},
+		"custom and timeout": {[]ClientOption{WithHTTPClient(custom), WithTimeout(time.Second)}, time.Second, "Go-http-client/1.1", ""},
+		"headers":            {[]ClientOption{WithUserAgent("sublate/1.0"), WithHeader("X-Source", "cms")}, DefaultTimeout, "sublate/1.0", "cms"},
+		"transport":          {[]ClientOption{WithTransport(http.DefaultTransport), WithHeader("X-Source", "cms")}, DefaultTimeout, "Go-http-client/1.1", "cms"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("User-Agent"); got != tc.agent {
+					t.Errorf("got user agent %q, want %q", got, tc.agent)
+				}
+				if got := r.Header.Get("X-Source"); got != tc.header {
+					t.Errorf("got header %q, want %q", got, tc.header)
+				}
+			}))
+			defer server.Close()
+
+			hc := NewConfig(tc.opts...).NewHTTPClient()
+			if hc.Timeout != tc.timeout {
+				t.Errorf("got timeout %v, want %v", hc.Timeout, tc.timeout)
+			}
+			res, err := hc.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+		})
+	}
+
+	if custom.Timeout != 5*time.Second || custom.Transport != nil {
+		t.Error("the custom http client must not be modified")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("stub-register", stubFactory)
+	if !slices.Contains(Providers(), "stub-register") {
+		t.Errorf("provider not listed: %v", Providers())
+	}
+
+	cases := map[string]struct {
+		name    Provider
+		factory ClientFactory
+	}{
+		"duplicate":   {"stub-register", stubFactory},
+		"nil factory": {"stub-nil", nil},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			Register(tc.name, tc.factory)
+		})
+	}
+}
+
+func TestInstances(t *testing.T) {
+	Register("stub-instances", stubFactory)
+
+	free, err := NewInstance("stub-free", "stub-instances", WithAPIKey("free:fx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewInstance("stub-pro", "stub-instances", WithAPIKey("pro")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Instance("stub-free")
+	if err != nil || got != free || got.(*stubClient).cfg.APIKey != "free:fx" {
+		t.Errorf("got %v, %v", got, err)
+	}
+	pro, _ := Instance("stub-pro")
+	if pro.(*stubClient).cfg.APIKey != "pro" {
+		t.Errorf("instances are not configured independently")
+	}
+	if names := Instances(); !slices.Contains(names, "stub-free") || !slices.Contains(names, "stub-pro") {
+		t.Errorf("got instances %v", names)
+	}
+
+	var terr *serr.TranslateError
+	if _, err := Instance("missing"); !errors.As(err, &terr) || terr.Code != serr.ErrInvalidProvider {
+		t.Errorf("expected ErrInvalidProvider, got %v", err)
+	}
+	if _, err := NewInstance("stub-bad", "not-registered"); !errors.As(err, &terr) || terr.Code != serr.ErrInvalidProvider {
+		t.Errorf("expected ErrInvalidProvider, got %v", err)
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Register(Provider(fmt.Sprintf("stub-concurrent-%d", i)), stubFactory)
+			NewInstance(fmt.Sprintf("stub-concurrent-%d", i), Provider(fmt.Sprintf("stub-concurrent-%d", i)))
+			Providers()
+			Instances()
+		}()
+	}
+	wg.Wait()
+
+	for i := range 20 {
+		if _, err := Instance(fmt.Sprintf("stub-concurrent-%d", i)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCapabilitiesZeroValue(t *testing.T) {
+	var caps Capabilities
+	for _, l := range []lang.Language{"", lang.AutoDetect, lang.English} {
+		if !caps.SupportsSource(l) || !caps.SupportsTarget(l) {
+			t.Errorf("the zero value should allow %q", l)
+		}
+	}
+	caps.NoAutoDetect = true
+	if caps.SupportsSource("") || caps.SupportsSource(lang.AutoDetect) || !caps.SupportsSource(lang.English) {
+		t.Error("NoAutoDetect should only reject a missing source language")
+	}
+}
@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+// Rate allows Count tokens per Per (a second when 0), up to Count at once. 0 Count for no limit
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// RateLimit throttles a client in requests and in characters, as counted by GetCharCount. ex: DeepL plans
+//
+//	RateLimit{Requests: Rate{5, time.Second}, Characters: Rate{100_000, time.Minute}}
+type RateLimit struct {
+	Requests   Rate
+	Characters Rate
+}
+
+// ErrDeadlineTooClose is returned by Limiter.Wait when the turn of a call comes after the deadline of its context.
+// it wraps context.DeadlineExceeded, the provider is never reached so breakers don't record it
+var ErrDeadlineTooClose = fmt.Errorf("rate limit: turn after the deadline: %w", context.DeadlineExceeded)
+
+// Limiter is a token bucket for requests and one for characters, shared by every goroutine using the clients it limits
+type Limiter struct {
+	mu       sync.Mutex
+	requests bucket
+	chars    bucket
+	now      func() time.Time
+}
+
+func NewLimiter(limit RateLimit) *Limiter {
+	now := time.Now()
+	return &Limiter{
+		requests: newBucket(limit.Requests, now),
+		chars:    newBucket(limit.Characters, now),
+		now:      time.Now,
+	}
+}
+
+// Wait blocks until a request of chars characters is allowed. it returns the error of ctx when ctx is done first
+// and ErrDeadlineTooClose when its deadline comes before the turn of the request, the tokens are then given back
+func (l *Limiter) Wait(ctx context.Context, chars int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	wait := max(l.requests.take(now, 1), l.chars.take(now, float64(chars)))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(wait)) {
+		l.cancel(chars)
+		return ErrDeadlineTooClose
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(chars)
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) cancel(chars int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests.give(1)
+	l.chars.give(float64(chars))
+}
+
+type bucket struct {
+	rate   float64 // tokens per second, 0 for no limit
+	burst  float64
+	tokens float64 // negative when requests are waiting
+	last   time.Time
+}
+
+func newBucket(r Rate, now time.Time) bucket {
+	if r.Count <= 0 {
+		return bucket{}
+	}
+	per := r.Per
+	if per <= 0 {
+		per = time.Second
+	}
+	return bucket{rate: float64(r.Count) / per.Seconds(), burst: float64(r.Count), tokens: float64(r.Count), last: now}
+}
+
+// take removes n tokens, going in debt when there aren't enough, and returns how long until the debt is paid back
+func (b *bucket) take(now time.Time, n float64) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *bucket) give(n float64) {
+	if b.rate > 0 {
+		b.tokens = min(b.burst, b.tokens+n)
+	}
+}
+
+// RateLimiting makes each call wait for l, Translate takes the characters of the request.
+// errors of the context are returned as ErrNetwork errors, like a request canceled while sent.
+// a deadline too close to wait for l fails with ErrThrottled, it is not retryable
+func RateLimiting(l *Limiter) Middleware {
+	return func(next SyncClient) SyncClient {
+		return limitedClient{next, l}
+	}
+}
+
+// RateLimitingAsync makes each call wait for l, status checks and downloads count as requests without characters
+func RateLimitingAsync(l *Limiter) AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return limitedAsyncClient{next, l}
+	}
+}
+
+func wait(ctx context.Context, l *Limiter, op string, client Client, chars int) error {
+	if err := l.Wait(ctx, chars); err != nil {
+		if errors.Is(err, ErrDeadlineTooClose) {
+			return serr.New(serr.ErrThrottled, op, string(client.Name()), err)
+		}
+		return serr.New(serr.ErrNetwork, op, string(client.Name()), err)
+	}
+	return nil
+}
+
+type limitedClient struct {
+	SyncClient
+	limiter *Limiter
+}
+
+func (c limitedClient) Unwrap() Client { return c.SyncClient }
+
+func (c limitedClient) Translate(ctx context.Context, req Request) (Response, error) {
+	if err := wait(ctx, c.limiter, "Translate", c, c.GetCharCount(req)); err != nil {
+		return Response{}, err
+	}
+	return c.SyncClient.Translate(ctx, req)
+}
+
+type limitedAsyncClient struct {
+	AsyncClient
+	limiter *Limiter
+}
+
+func (c limitedAsyncClient) Unwrap() Client { return c.AsyncClient }
+
+func (c limitedAsyncClient) AsyncTranslate(ctx context.Context, req Request) (AsyncResponse, error) {
+	if err := wait(ctx, c.limiter, "AsyncTranslate", c, c.GetCharCount(req)); err != nil {
+		return AsyncResponse{}, err
+	}
+	return c.AsyncClient.AsyncTranslate(ctx, req)
+}
+
+func (c limitedAsyncClient) CheckStatus(ctx context.Context, obj AsyncResponse) (JobStatus, error) {
+	if err := wait(ctx, c.limiter, "CheckStatus", c, 0); err != nil {
+		return JobStatus{}, err
+	}
+	return c.AsyncClient.CheckStatus(ctx, obj)
+}
+
+func (c limitedAsyncClient) GetResult(ctx context.Context, obj AsyncResponse) (Response, error) {
+	if err := wait(ctx, c.limiter, "GetResult", c, 0); err != nil {
+		return Response{}, err
+	}
+	return c.AsyncClient.GetResult(ctx, obj)
+}
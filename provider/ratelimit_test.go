@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+func TestBucket(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		rate  Rate
+		takes []float64     // tokens taken at start
+		after time.Duration // when the last take happens
+		want  time.Duration // wait of the last take
+	}{
+		"no limit":          {Rate{}, []float64{1000, 1000}, 0, 0},
+		"burst":             {Rate{3, time.Second}, []float64{1, 1, 1}, 0, 0},
+		"over the burst":    {Rate{2, time.Second}, []float64{1, 1, 1}, 0, 500 * time.Millisecond},
+		"refilled":          {Rate{2, time.Second}, []float64{1, 1, 1}, 500 * time.Millisecond, 0},
+		"per minute":        {Rate{60, time.Minute}, []float64{60, 2}, 0, 2 * time.Second},
+		"default period":    {Rate{Count: 10}, []float64{10, 5}, 0, 500 * time.Millisecond},
+		"larger than burst": {Rate{10, time.Second}, []float64{30}, 0, 2 * time.Second},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := newBucket(tc.rate, start)
+			var wait time.Duration
+			for i, n := range tc.takes {
+				now := start
+				if i == len(tc.takes)-1 {
+					now = now.Add(tc.after)
+				}
+				wait = b.take(now, n)
+			}
+			if wait != tc.want {
+				t.Errorf("got %v, want %v", wait, tc.want)
+			}
+		})
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	l := NewLimiter(RateLimit{Requests: Rate{1, 50 * time.Millisecond}})
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 3 {
+		if err := l.Wait(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests at 1 per 50ms took %v", elapsed)
+	}
+
+	// a deadline before the turn of the request fails right away and gives the token back
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(short, 0); !errors.Is(err, ErrDeadlineTooClose) {
+		t.Errorf("expected ErrDeadlineTooClose, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if wait := l.requests.take(time.Now(), 1); wait != 0 {
+		t.Errorf("the token of the failed request was not given back, wait %v", wait)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(canceled, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+}
+
+func TestRateLimiting(t *testing.T) {
+	l := NewLimiter(RateLimit{Requests: Rate{5, time.Hour}, Characters: Rate{100, time.Hour}})
+	inner := &countingClient{chars: 10}
+	client := Wrap(inner, RateLimiting(l), RateLimitingAsync(l)).(SyncClient)
+
+	// the workers share the limit
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if _, err := client.Translate(context.Background(), Request{Text: []string{"hello"}}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if len(inner.calls) != 5 {
+		t.Fatalf("expected 5 calls, got %d", len(inner.calls))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := client.Translate(ctx, Request{Text: []string{"hello"}})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrThrottled || !errors.Is(err, ErrDeadlineTooClose) || serr.Retryable(err) {
+		t.Errorf("expected an ErrThrottled deadline error, got %v", err)
+	}
+
+	// characters are limited too
+	l = NewLimiter(RateLimit{Characters: Rate{15, time.Hour}})
+	client = Wrap(inner, RateLimiting(l), nil).(SyncClient)
+	if _, err := client.Translate(ctx, Request{Text: []string{"hello"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Translate(ctx, Request{Text: []string{"hello"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("20 characters should not fit in 15, got %v", err)
+	}
+}
+
+func TestGetClientRateLimit(t *testing.T) {
+	Register("stub-limited", func(cfg Config) (Client, error) { return &echoClient{}, nil })
+
+	client, err := GetClient("stub-limited", WithRateLimit(RateLimit{Requests: Rate{Count: 1, Per: time.Hour}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncC := client.(SyncClient)
+	if _, err := syncC.Translate(context.Background(), Request{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := syncC.Translate(ctx, Request{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second request should wait an hour, got %v", err)
+	}
+	if _, ok := client.(AsyncClient); !ok {
+		t.Error("the limited client lost document support")
+	}
+}
+
+// countingClient is an echoClient counting a fixed number of characters per request
+type countingClient struct {
+	echoClient
+	chars int
+}
+
+func (c *countingClient) GetCharCount(Request) int { return c.chars }
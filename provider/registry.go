@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+var (
+	mu        sync.RWMutex
+	registry  = map[Provider]ClientFactory{}
+	instances = map[string]Client{}
+)
+
+// Register makes a provider available by name. it panics if the factory is nil or the provider is registered twice, like database/sql drivers
+func Register(name Provider, factory ClientFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if factory == nil {
+		panic("provider: Register factory is nil for " + string(name))
+	}
+	if _, dup := registry[name]; dup {
+		panic("provider: Register called twice for " + string(name))
+	}
+	registry[name] = factory
+}
+
+// Providers returns the registered providers, sorted
+func Providers() []Provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Sorted(maps.Keys(registry))
+}
+
+// GetClient builds a client of a registered provider. ex: GetClient(DeepL, WithAPIKey(key), WithTimeout(30*time.Second)).
+// with a rate limit or retries the client is wrapped with the RateLimiting and Retrying middlewares, each attempt waiting for the limiter
+func GetClient(name Provider, opts ...ClientOption) (Client, error) {
+	mu.RLock()
+	factory, ok := registry[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, serr.New(serr.ErrInvalidProvider, "GetClient", "", fmt.Errorf("%s is not a valid provider", name))
+	}
+	cfg := NewConfig(opts...)
+	client, err := factory(cfg)
+	if err != nil {
+		return client, err
+	}
+	if cfg.Limiter != nil {
+		client = Wrap(client, RateLimiting(cfg.Limiter), RateLimitingAsync(cfg.Limiter))
+	}
+	if cfg.Retry.MaxAttempts > 1 {
+		client = Wrap(client, Retrying(cfg.Retry), RetryingAsync(cfg.Retry))
+	}
+	return client, nil
+}
+
+// NewInstance builds a client and keeps it under an instance name, so several clients of one provider can be configured.
+// ex: NewInstance("deepl-free", DeepL, WithAPIKey(freeKey)). an existing instance with the same name is replaced
+func NewInstance(instance string, name Provider, opts ...ClientOption) (Client, error) {
+	if instance == "" {
+		return nil, serr.New(serr.ErrInvalidRequest, "NewInstance", string(name), fmt.Errorf("empty instance name"))
+	}
+	client, err := GetClient(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	instances[instance] = client
+	return client, nil
+}
+
+// Instance returns the client created by NewInstance
+func Instance(instance string) (Client, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	client, ok := instances[instance]
+	if !ok {
+		return nil, serr.New(serr.ErrInvalidProvider, "Instance", "", fmt.Errorf("no client instance named %q", instance))
+	}
+	return client, nil
+}
+
+// Instances returns the names of the client instances, sorted
+func Instances() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Sorted(maps.Keys(instances))
+}
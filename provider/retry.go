@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+// Retry retries the calls failing with an error a later attempt may fix on the same client:
+// network errors, rate limiting (429) and 5xx responses. exceeded quotas and open circuits fail right away
+type Retry struct {
+	MaxAttempts int           // attempts including the first one, 0 or 1 disables retries
+	Backoff     time.Duration // wait before the first retry, doubled on each attempt
+	MaxBackoff  time.Duration // 0 for no cap
+}
+
+// delay returns the wait before the retry following attempt n, starting at 1
+func (r Retry) delay(n int) time.Duration {
+	d := r.Backoff
+	for range n - 1 {
+		if r.MaxBackoff > 0 && d >= r.MaxBackoff {
+			break
+		}
+		d *= 2
+	}
+	if r.MaxBackoff > 0 {
+		d = min(d, r.MaxBackoff)
+	}
+	return d
+}
+
+func (r Retry) retries(err error) bool {
+	var terr *serr.TranslateError
+	return serr.Retryable(err) && errors.As(err, &terr) && terr.Code != serr.ErrQuotaExceeded && terr.Code != serr.ErrCircuitOpen
+}
+
+// Retrying retries Translate with r
+func Retrying(r Retry) Middleware {
+	return func(next SyncClient) SyncClient {
+		return retryingClient{next, r}
+	}
+}
+
+// RetryingAsync retries every call of the async flow with r
+func RetryingAsync(r Retry) AsyncMiddleware {
+	return func(next AsyncClient) AsyncClient {
+		return retryingAsyncClient{next, r}
+	}
+}
+
+// retry calls call until it succeeds, fails with an error r doesn't retry or runs out of attempts.
+// the last error is returned, the error of the context if it ends while waiting
+func retry[T any](ctx context.Context, r Retry, op string, client Client, call func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := call()
+		if err == nil || attempt >= r.MaxAttempts || !r.retries(err) || ctx.Err() != nil {
+			return res, err
+		}
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, serr.New(serr.ErrNetwork, op, string(client.Name()), ctx.Err())
+		case <-time.After(r.delay(attempt)):
+		}
+	}
+}
+
+type retryingClient struct {
+	SyncClient
+	retry Retry
+}
+
+func (c retryingClient) Unwrap() Client { return c.SyncClient }
+
+func (c retryingClient) Translate(ctx context.Context, req Request) (Response, error) {
+	return retry(ctx, c.retry, "Translate", c, func() (Response, error) { return c.SyncClient.Translate(ctx, req) })
+}
+
+type retryingAsyncClient struct {
+	AsyncClient
+	retry Retry
+}
+
+func (c retryingAsyncClient) Unwrap() Client { return c.AsyncClient }
+
+func (c retryingAsyncClient) AsyncTranslate(ctx context.Context, req Request) (AsyncResponse, error) {
+	return retry(ctx, c.retry, "AsyncTranslate", c, func() (AsyncResponse, error) { return c.AsyncClient.AsyncTranslate(ctx, req) })
+}
+
+func (c retryingAsyncClient) CheckStatus(ctx context.Context, obj AsyncResponse) (JobStatus, error) {
+	return retry(ctx, c.retry, "CheckStatus", c, func() (JobStatus, error) { return c.AsyncClient.CheckStatus(ctx, obj) })
+}
+
+func (c retryingAsyncClient) GetResult(ctx context.Context, obj AsyncResponse) (Response, error) {
+	return retry(ctx, c.retry, "GetResult", c, func() (Response, error) { return c.AsyncClient.GetResult(ctx, obj) })
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+)
+
+// flakyClient fails its first calls with err
+type flakyClient struct {
+	echoClient
+	failures int
+	err      error
+}
+
+func (c *flakyClient) Translate(ctx context.Context, req Request) (Response, error) {
+	c.record("Translate")
+	if len(c.calls) <= c.failures {
+		return Response{}, c.err
+	}
+	return Response{Text: req.Text}, nil
+}
+
+func TestRetryDelay(t *testing.T) {
+	r := Retry{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 60: 5 * time.Second} {
+		if got := r.delay(n); got != want {
+			t.Errorf("delay(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestRetrying(t *testing.T) {
+	network := serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("connection reset"))
+
+	cases := map[string]struct {
+		failures int
+		err      error
+		calls    int
+		ok       bool
+	}{
+		"success":       {0, nil, 1, true},
+		"recovers":      {2, network, 3, true},
+		"out of tries":  {5, network, 3, false},
+		"server error":  {1, serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 503}), 2, true},
+		"bad request":   {1, serr.New(serr.ErrHTTP, "Translate", "stub", &serr.HTTPError{StatusCode: 400}), 1, false},
+		"quota":         {1, serr.New(serr.ErrQuotaExceeded, "Translate", "stub", nil), 1, false},
+		"circuit open":  {1, serr.New(serr.ErrCircuitOpen, "Translate", "stub", nil), 1, false},
+		"not sublaterr": {1, errors.New("boom"), 1, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			inner := &flakyClient{failures: tc.failures, err: tc.err}
+			client := Retrying(Retry{MaxAttempts: 3, Backoff: time.Millisecond})(inner)
+
+			_, err := client.Translate(context.Background(), Request{Text: []string{"hi"}})
+			if (err == nil) != tc.ok {
+				t.Errorf("got %v, want ok %v", err, tc.ok)
+			}
+			if len(inner.calls) != tc.calls {
+				t.Errorf("got %d calls, want %d", len(inner.calls), tc.calls)
+			}
+		})
+	}
+}
+
+func TestRetryingCanceled(t *testing.T) {
+	inner := &flakyClient{failures: 5, err: serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("connection reset"))}
+	client := Retrying(Retry{MaxAttempts: 5, Backoff: time.Hour})(inner)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Translate(ctx, Request{Text: []string{"hi"}})
+	if !errors.Is(err, context.DeadlineExceeded) || len(inner.calls) != 1 {
+		t.Errorf("the backoff should end with the context, got %v after %d calls", err, len(inner.calls))
+	}
+}
+
+func TestGetClientRetry(t *testing.T) {
+	Register("retry-stub", func(cfg Config) (Client, error) {
+		return &flakyClient{failures: 1, err: serr.New(serr.ErrNetwork, "Translate", "stub", errors.New("connection reset"))}, nil
+	})
+
+	client, err := GetClient("retry-stub", WithRetry(Retry{MaxAttempts: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.(SyncClient).Translate(context.Background(), Request{Text: []string{"hi"}}); err != nil {
+		t.Errorf("the client should retry, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package provider
+
+import "context"
+
+// Usage is the account usage for the current billing period. a limit of 0 means no limit is reported
+type Usage struct {
+	CharacterCount int64
+	CharacterLimit int64
+	DocumentCount  int64
+	DocumentLimit  int64
+}
+
+// RemainingCharacters returns how many characters can still be translated, -1 if there is no limit
+func (u Usage) RemainingCharacters() int64 {
+	if u.CharacterLimit == 0 {
+		return -1
+	}
+	return max(u.CharacterLimit-u.CharacterCount, 0)
+}
+
+// RemainingDocuments returns how many documents can still be translated, -1 if there is no limit
+func (u Usage) RemainingDocuments() int64 {
+	if u.DocumentLimit == 0 {
+		return -1
+	}
+	return max(u.DocumentLimit-u.DocumentCount, 0)
+}
+
+// UsageReporter is implemented by clients that can report the account usage, use As to find it under wrappers
+type UsageReporter interface {
+	Usage(context.Context) (Usage, error)
+}
@@ -0,0 +1,110 @@
+package tm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	segtext "github.com/o0n1x/sublate-go/internal/segtext"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// Client is a SyncClient answering text segments from a Memory, the other segments are sent to the wrapped client
+// and their translations added to the memory. only exact matches are used unless Fuzzy is set.
+// Segments[i].MatchScore holds the score of the unit a segment comes from. document requests go straight to the wrapped client
+type Client struct {
+	provider.SyncClient
+	memory   *Memory
+	minScore float64
+}
+
+func (c *Client) Unwrap() provider.Client { return c.SyncClient }
+
+// Option configures a Client
+type Option func(*Client)
+
+// Fuzzy also answers segments with the unit of a similar source scoring at least minScore (ex: DefaultMinScore).
+// the translation of that other source is used as is, MatchScore tells these segments apart for review
+func Fuzzy(minScore float64) Option {
+	return func(c *Client) { c.minScore = minScore }
+}
+
+// Wrap returns client looking up m first. the result is also an AsyncClient when client is one
+func Wrap(client provider.SyncClient, m *Memory, opts ...Option) provider.SyncClient {
+	return provider.Wrap(client, Middleware(m, opts...), nil).(provider.SyncClient)
+}
+
+// Middleware returns the Client of m as a provider.Middleware, ex: to chain it with other middlewares
+func Middleware(m *Memory, opts ...Option) provider.Middleware {
+	return func(next provider.SyncClient) provider.SyncClient {
+		c := &Client{SyncClient: next, memory: m, minScore: 1}
+		for _, opt := range opts {
+			opt(c)
+		}
+		return c
+	}
+}
+
+// Translate looks up the segments when the source language is known, requests left to auto detection
+// are only written back when the provider reports the detected language
+func (c *Client) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	if req.ReqType == sformat.File || len(req.Binary) > 0 {
+		return c.SyncClient.Translate(ctx, req)
+	}
+
+	res := provider.Response{
+		Text:     make([]string, len(req.Text)),
+		Segments: make([]provider.SegmentMeta, len(req.Text)),
+		Provider: c.Name(),
+	}
+	known := req.From != "" && req.From != lang.AutoDetect
+	var misses []int
+	sub := req
+	sub.Text = nil
+	for i, text := range req.Text {
+		if known {
+			if m, ok := c.memory.Lookup(req.From, req.To, text, c.minScore); ok {
+				res.Text[i] = segtext.Rewrap(text, m.Target)
+				res.Segments[i] = provider.SegmentMeta{DetectedLanguage: m.From, MatchScore: m.Score}
+				continue
+			}
+		}
+		misses = append(misses, i)
+		sub.Text = append(sub.Text, text)
+	}
+	if len(misses) == 0 {
+		return res, nil
+	}
+
+	translated, err := c.SyncClient.Translate(ctx, sub)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	if len(translated.Text) != len(sub.Text) {
+		return provider.Response{}, serr.New(serr.ErrInvalidResponse, "Translate", string(c.Name()), fmt.Errorf("got %d translations for %d texts", len(translated.Text), len(sub.Text)))
+	}
+
+	now := time.Now()
+	for j, i := range misses {
+		res.Text[i] = translated.Text[j]
+		from := req.From
+		if j < len(translated.Segments) {
+			res.Segments[i] = translated.Segments[j]
+			if !known {
+				from = translated.Segments[j].DetectedLanguage
+			}
+		}
+		if from != "" && from != lang.AutoDetect {
+			c.memory.Add(Unit{From: from, To: req.To, Source: req.Text[i], Target: translated.Text[j], Created: now})
+		}
+	}
+	res.BilledCharacters = translated.BilledCharacters
+	res.Model = translated.Model
+	if translated.Provider != "" {
+		res.Provider = translated.Provider
+	}
+	return res, nil
+}
@@ -0,0 +1,79 @@
+package tm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+func TestClient(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Save the file", Target: "Datei speichern"})
+	inner := &fake.Upper{}
+	client := Wrap(inner, m)
+
+	resp, err := client.Translate(context.Background(), provider.Request{
+		Text: []string{" Save the file", "Save the files", "Quit"},
+		From: lang.English,
+		To:   lang.German,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{" Datei speichern", "SAVE THE FILES", "QUIT"}; !reflect.DeepEqual(resp.Text, want) {
+		t.Errorf("got %q, want %q", resp.Text, want)
+	}
+	// similar sources aren't used by default
+	if len(inner.Requests) != 1 || !reflect.DeepEqual(inner.Requests[0].Text, []string{"Save the files", "Quit"}) {
+		t.Errorf("only misses should be sent: %+v", inner.Requests)
+	}
+	if s := resp.Segments; s[0].MatchScore != 1 || s[1].MatchScore != 0 || s[2].MatchScore != 0 || s[2].BilledCharacters != 4 {
+		t.Errorf("unexpected segments %+v", s)
+	}
+
+	// machine translations are written back
+	if match, ok := m.Lookup(lang.English, lang.German, "Quit", 1); !ok || match.Target != "QUIT" {
+		t.Errorf("translation not added to the memory: %+v", match)
+	}
+}
+
+func TestClientFuzzy(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Save the file", Target: "Datei speichern"})
+	inner := &fake.Upper{}
+	client := Wrap(inner, m, Fuzzy(DefaultMinScore))
+
+	resp, err := client.Translate(context.Background(), provider.Request{Text: []string{"Save the file.", "Quit"}, From: lang.English, To: lang.German})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Datei speichern", "QUIT"}; !reflect.DeepEqual(resp.Text, want) {
+		t.Errorf("got %q, want %q", resp.Text, want)
+	}
+	if score := resp.Segments[0].MatchScore; score < DefaultMinScore || score >= 1 {
+		t.Errorf("fuzzy matches should report their score, got %v", score)
+	}
+}
+
+func TestClientAutoDetect(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Hello", Target: "Hallo"})
+	inner := &fake.Upper{}
+	client := Wrap(inner, m)
+
+	resp, err := client.Translate(context.Background(), provider.Request{Text: []string{"Hello", "Bye"}, To: lang.German})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the source language is unknown before the provider detects it
+	if len(inner.Requests) != 1 || len(inner.Requests[0].Text) != 2 || resp.Text[0] != "HELLO" {
+		t.Errorf("segments of unknown languages should not be looked up: %+v", inner.Requests)
+	}
+	if _, ok := m.Lookup(lang.English, lang.German, "Bye", 1); !ok {
+		t.Error("translation not added with the detected language")
+	}
+}
@@ -0,0 +1,228 @@
+// Package tm is a translation memory: source/target pairs per language pair, looked up by similarity before calling a provider.
+// memories are imported and exported as TMX 1.4
+package tm
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// DefaultMinScore is the usual fuzzy match threshold of localization tools, see Fuzzy
+const DefaultMinScore = 0.85
+
+// Unit is a translation unit: a source segment and its translation
+type Unit struct {
+	From    lang.Language
+	To      lang.Language
+	Source  string
+	Target  string
+	Created time.Time // zero if unknown
+}
+
+// Match is a unit similar to a looked up text, Score is 1 for an exact match
+type Match struct {
+	Unit
+	Score float64
+}
+
+// pair is a language pair of base languages, the units of its regional variants are stored together
+type pair struct {
+	from, to lang.Language
+}
+
+func basePair(from, to lang.Language) pair {
+	return pair{from.Base(), to.Base()}
+}
+
+// Memory holds units by language pair, a source is stored once per pair. it is safe for concurrent use
+type Memory struct {
+	mu    sync.RWMutex
+	units map[pair]map[string][]Unit // normalized source -> units of the regional variants of the pair
+}
+
+func New() *Memory {
+	return &Memory{units: make(map[pair]map[string][]Unit)}
+}
+
+// normalize returns the text a source is stored under, surrounding whitespace is ignored
+func normalize(text string) string {
+	return strings.TrimSpace(text)
+}
+
+// Add stores a unit, replacing the unit with the same source in the language pair
+func (m *Memory) Add(u Unit) {
+	src := normalize(u.Source)
+	if src == "" {
+		return
+	}
+	u.Source, u.Target = src, normalize(u.Target)
+	p := basePair(u.From, u.To)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.units[p] == nil {
+		m.units[p] = make(map[string][]Unit)
+	}
+	variants := m.units[p][src]
+	for i, v := range variants {
+		if v.From == u.From && v.To == u.To {
+			variants[i] = u
+			return
+		}
+	}
+	m.units[p][src] = append(variants, u)
+}
+
+// compatible reports whether a unit in language a can be used for b: they are equal or one is the base language of the other.
+// ex: DE and DE-DE are compatible, EN-GB and EN-US are not
+func compatible(a, b lang.Language) bool {
+	return a == b || a == b.Base() || a.Base() == b
+}
+
+// variant returns the unit of variants usable for the pair, preferring the exact target then the exact source language
+func variant(variants []Unit, from, to lang.Language) (Unit, bool) {
+	best, rank := Unit{}, -1
+	for _, u := range variants {
+		if !compatible(u.From, from) || !compatible(u.To, to) {
+			continue
+		}
+		r := 0
+		if u.To == to {
+			r += 2
+		}
+		if u.From == from {
+			r++
+		}
+		if r > rank {
+			best, rank = u, r
+		}
+	}
+	return best, rank >= 0
+}
+
+// Lookup returns the unit of the language pair most similar to text, if its score is at least minScore.
+// languages are matched on their base language when one side has no region, see Matches
+func (m *Memory) Lookup(from, to lang.Language, text string, minScore float64) (Match, bool) {
+	matches := m.Matches(from, to, text, minScore, 1)
+	if len(matches) == 0 {
+		return Match{}, false
+	}
+	return matches[0], true
+}
+
+// Matches returns up to limit units (0 for all) scoring at least minScore, best first.
+// a unit matches a language that is equal or that is its base language, or the other way around: a DE-DE unit is used for DE
+func (m *Memory) Matches(from, to lang.Language, text string, minScore float64, limit int) []Match {
+	text = normalize(text)
+	if text == "" {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	units := m.units[basePair(from, to)]
+	if u, ok := variant(units[text], from, to); ok && (limit == 1 || minScore >= 1) {
+		return []Match{{u, 1}}
+	}
+
+	var matches []Match
+	n := utf8.RuneCountInString(text)
+	for src, variants := range units {
+		// the edit distance is at least the length difference
+		sn := utf8.RuneCountInString(src)
+		if 1-float64(abs(n-sn))/float64(max(n, sn)) < minScore {
+			continue
+		}
+		u, ok := variant(variants, from, to)
+		if !ok {
+			continue
+		}
+		if score := Similarity(text, src); score >= minScore {
+			matches = append(matches, Match{u, score})
+		}
+	}
+	slices.SortFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Source, b.Source))
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Len returns the number of units
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, units := range m.units {
+		for _, variants := range units {
+			n += len(variants)
+		}
+	}
+	return n
+}
+
+// Units returns the units sorted by language pair and source
+func (m *Memory) Units() []Unit {
+	m.mu.RLock()
+	var all []Unit
+	for _, units := range m.units {
+		for _, variants := range units {
+			all = append(all, variants...)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(all, func(a, b Unit) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To), strings.Compare(a.Source, b.Source))
+	})
+	return all
+}
+
+// Similarity returns 1 minus the Levenshtein distance of a and b relative to the longest one, in runes.
+// 1 means equal, 0 nothing in common
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+}
+
+func levenshtein(a, b []rune) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	// one row of the distance matrix, b is the shortest
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur := min(row[j]+1, row[j-1]+1, prev+cost)
+			prev, row[j] = row[j], cur
+		}
+	}
+	return row[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
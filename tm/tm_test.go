@@ -0,0 +1,113 @@
+package tm
+
+import (
+	"math"
+	"testing"
+
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+func TestSimilarity(t *testing.T) {
+	cases := map[string]struct {
+		a, b string
+		want float64
+	}{
+		"equal":        {"hello", "hello", 1},
+		"empty":        {"", "", 1},
+		"one edit":     {"kitten", "sitten", 1 - 1.0/6},
+		"levenshtein":  {"kitten", "sitting", 1 - 3.0/7},
+		"runes":        {"héllo", "hello", 0.8},
+		"nothing":      {"abc", "xyz", 0},
+		"one is empty": {"abc", "", 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Similarity(tc.a, tc.b); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Save the file", Target: "Datei speichern"})
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Open the file", Target: "Datei öffnen"})
+	m.Add(Unit{From: lang.English, To: lang.French, Source: "Save the files", Target: "Enregistrer les fichiers"})
+
+	cases := map[string]struct {
+		from, to lang.Language
+		text     string
+		want     string
+		score    float64
+		ok       bool
+	}{
+		"exact":               {lang.English, lang.German, "Save the file", "Datei speichern", 1, true},
+		"surrounding spaces":  {lang.English, lang.German, " Save the file\n", "Datei speichern", 1, true},
+		"fuzzy":               {lang.English, lang.German, "Save the file.", "Datei speichern", 1 - 1.0/14, true},
+		"under the threshold": {lang.English, lang.German, "Close the window", "", 0, false},
+		"other language pair": {lang.English, lang.Spanish, "Save the file", "", 0, false},
+		"pairs are separate":  {lang.English, lang.French, "Save the files", "Enregistrer les fichiers", 1, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			match, ok := m.Lookup(tc.from, tc.to, tc.text, DefaultMinScore)
+			if ok != tc.ok || match.Target != tc.want || math.Abs(match.Score-tc.score) > 1e-9 {
+				t.Errorf("got %+v %v", match, ok)
+			}
+		})
+	}
+
+	matches := m.Matches(lang.English, lang.German, "Save the file", 0.5, 0)
+	if len(matches) != 2 || matches[0].Source != "Save the file" || matches[1].Source != "Open the file" {
+		t.Errorf("matches not sorted by score: %+v", matches)
+	}
+}
+
+func TestAddReplaces(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Hello", Target: "Hallo"})
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Hello ", Target: "Servus"})
+	if m.Len() != 1 {
+		t.Fatalf("expected 1 unit, got %d", m.Len())
+	}
+	if match, _ := m.Lookup(lang.English, lang.German, "Hello", 1); match.Target != "Servus" {
+		t.Errorf("unit not replaced: %+v", match)
+	}
+}
+
+func TestLookupRegions(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.EnglishUS, To: "DE-DE", Source: "Hello", Target: "Hallo"})
+	m.Add(Unit{From: lang.English, To: lang.EnglishUK, Source: "color", Target: "colour"})
+
+	cases := map[string]struct {
+		from, to lang.Language
+		text     string
+		want     string
+	}{
+		"base languages":     {lang.English, lang.German, "Hello", "Hallo"},
+		"exact languages":    {lang.EnglishUS, "DE-DE", "Hello", "Hallo"},
+		"other region":       {lang.EnglishUK, "DE-DE", "Hello", ""},
+		"regional target":    {lang.English, lang.EnglishUK, "color", "colour"},
+		"other target":       {lang.English, lang.EnglishUS, "color", ""},
+		"base of the target": {lang.English, lang.English, "color", "colour"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			match, _ := m.Lookup(tc.from, tc.to, tc.text, 1)
+			if match.Target != tc.want {
+				t.Errorf("got %+v, want %q", match, tc.want)
+			}
+		})
+	}
+
+	// regional variants of a pair are kept apart
+	m.Add(Unit{From: lang.English, To: lang.EnglishUS, Source: "color", Target: "color"})
+	if m.Len() != 3 {
+		t.Errorf("got %d units, want 3", m.Len())
+	}
+	if match, _ := m.Lookup(lang.English, lang.EnglishUS, "color", 1); match.Target != "color" {
+		t.Errorf("the exact target should win, got %+v", match)
+	}
+}
@@ -0,0 +1,158 @@
+package tm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+// TMX 1.4 document. inline elements of segments (bpt, ph...) are imported as their text, see segment
+type tmxDoc struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  tmxHeader `xml:"header"`
+	Units   []tmxUnit `xml:"body>tu"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type tmxUnit struct {
+	SrcLang      string       `xml:"srclang,attr,omitempty"`
+	CreationDate string       `xml:"creationdate,attr,omitempty"`
+	Variants     []tmxVariant `xml:"tuv"`
+}
+
+type tmxVariant struct {
+	Lang string  `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Seg  segment `xml:"seg"`
+}
+
+// segment is the text of a seg. the content of its inline elements is kept in place: the native code of
+// placeholders and paired tags (ex: <ph>{0}</ph> -> {0}) and the text of highlighted parts, so no text is lost
+type segment string
+
+func (s *segment) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var b strings.Builder
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				*s = segment(b.String())
+				return nil
+			}
+			depth--
+		}
+	}
+}
+
+const (
+	allLanguages = "*all*"
+	tmxDate      = "20060102T150405Z"
+)
+
+// Import adds the units of a TMX document. a tu gives a unit from its source variant to each other variant,
+// the source is the srclang of the tu or header, or the first variant when it is *all*
+func (m *Memory) Import(r io.Reader) error {
+	var doc tmxDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return serr.New(serr.ErrInvalidFormat, "Import", "", fmt.Errorf("invalid TMX: %w", err))
+	}
+
+	for i, tu := range doc.Units {
+		srcLang := tu.SrcLang
+		if srcLang == "" {
+			srcLang = doc.Header.SrcLang
+		}
+		src := -1
+		for j, v := range tu.Variants {
+			if srcLang == allLanguages || strings.EqualFold(v.Lang, srcLang) {
+				src = j
+				break
+			}
+		}
+		if src < 0 {
+			return serr.New(serr.ErrInvalidFormat, "Import", "", fmt.Errorf("tu %d has no %s variant", i+1, srcLang))
+		}
+
+		created, _ := time.Parse(tmxDate, tu.CreationDate)
+		for j, v := range tu.Variants {
+			if j == src {
+				continue
+			}
+			m.Add(Unit{
+				From:    fromTag(tu.Variants[src].Lang),
+				To:      fromTag(v.Lang),
+				Source:  string(tu.Variants[src].Seg),
+				Target:  string(v.Seg),
+				Created: created,
+			})
+		}
+	}
+	return nil
+}
+
+// Export writes the units as a TMX 1.4 document, one tu per unit
+func (m *Memory) Export(w io.Writer) error {
+	doc := tmxDoc{
+		Version: "1.4",
+		Header: tmxHeader{
+			CreationTool:        "sublate-go",
+			CreationToolVersion: "1",
+			SegType:             "sentence",
+			OTMF:                "sublate-go",
+			AdminLang:           "en-US",
+			SrcLang:             allLanguages,
+			DataType:            "plaintext",
+		},
+	}
+	for _, u := range m.Units() {
+		tu := tmxUnit{
+			SrcLang: u.From.BCP47(),
+			Variants: []tmxVariant{
+				{Lang: u.From.BCP47(), Seg: segment(u.Source)},
+				{Lang: u.To.BCP47(), Seg: segment(u.Target)},
+			},
+		}
+		if !u.Created.IsZero() {
+			tu.CreationDate = u.Created.UTC().Format(tmxDate)
+		}
+		doc.Units = append(doc.Units, tu)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return serr.New(serr.ErrIO, "Export", "", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return serr.New(serr.ErrIO, "Export", "", err)
+	}
+	return nil
+}
+
+// fromTag converts a BCP 47 tag to a Language. ex: en-US -> EN-US, lookups match it with EN
+func fromTag(tag string) lang.Language {
+	return lang.Language(strings.ToUpper(strings.ReplaceAll(tag, "_", "-")))
+}
@@ -0,0 +1,95 @@
+package tm
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	lang "github.com/o0n1x/sublate-go/lang"
+)
+
+const sampleTMX = `<?xml version="1.0" encoding="UTF-8"?>
+<tmx version="1.4">
+  <header creationtool="tool" creationtoolversion="1" segtype="sentence" o-tmf="tool" adminlang="en-US" srclang="en-US" datatype="plaintext"/>
+  <body>
+    <tu creationdate="20240102T030405Z">
+      <tuv xml:lang="en-US"><seg>Hello world</seg></tuv>
+      <tuv xml:lang="de-DE"><seg>Hallo Welt</seg></tuv>
+      <tuv xml:lang="fr-FR"><seg>Bonjour le monde</seg></tuv>
+    </tu>
+    <tu srclang="de">
+      <tuv xml:lang="en"><seg>Click <bpt i="1">&lt;b&gt;</bpt>here<ept i="1">&lt;/b&gt;</ept></seg></tuv>
+      <tuv xml:lang="de"><seg>Hier klicken</seg></tuv>
+    </tu>
+    <tu>
+      <tuv xml:lang="en-US"><seg>Delete <ph x="1">{0}</ph> files</seg></tuv>
+      <tuv xml:lang="de-DE"><seg><ph x="1">{0}</ph> Dateien löschen</seg></tuv>
+    </tu>
+  </body>
+</tmx>`
+
+func TestImport(t *testing.T) {
+	m := New()
+	if err := m.Import(strings.NewReader(sampleTMX)); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []Unit{
+		{From: "DE", To: "EN", Source: "Hier klicken", Target: "Click <b>here</b>"},
+		{From: "EN-US", To: "DE-DE", Source: "Delete {0} files", Target: "{0} Dateien löschen"},
+		{From: "EN-US", To: "DE-DE", Source: "Hello world", Target: "Hallo Welt", Created: created},
+		{From: "EN-US", To: "FR-FR", Source: "Hello world", Target: "Bonjour le monde", Created: created},
+	}
+	if got := m.Units(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// regional tags are found with base languages
+	if match, ok := m.Lookup(lang.English, lang.German, "Hello world", 1); !ok || match.Target != "Hallo Welt" {
+		t.Errorf("got %+v, %v", match, ok)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	cases := map[string]string{
+		"not xml":          "hello",
+		"missing language": `<tmx version="1.4"><header srclang="ja"/><body><tu><tuv xml:lang="en"><seg>a</seg></tuv></tu></body></tmx>`,
+	}
+	for name, doc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var terr *serr.TranslateError
+			if err := New().Import(strings.NewReader(doc)); !errors.As(err, &terr) || terr.Code != serr.ErrInvalidFormat {
+				t.Errorf("expected ErrInvalidFormat, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	m := New()
+	m.Add(Unit{From: lang.EnglishUS, To: lang.ChineseSimplified, Source: "Tom & Jerry <3", Target: "汤姆和杰瑞", Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+	m.Add(Unit{From: lang.English, To: lang.German, Source: "Hello", Target: "Hallo"})
+
+	var buf bytes.Buffer
+	if err := m.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<tmx version="1.4">`, `srclang="*all*"`, `xml:lang="zh-Hans"`, `creationdate="20240102T030405Z"`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("export is missing %s:\n%s", s, buf.String())
+		}
+	}
+
+	imported := New()
+	if err := imported.Import(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported.Units(), m.Units()) {
+		t.Errorf("got %+v, want %+v", imported.Units(), m.Units())
+	}
+}
@@ -0,0 +1,56 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	caps := provider.Capabilities{
+		Formats:         []format.Format{format.Text},
+		SourceLanguages: []lang.Language{lang.English},
+		TargetLanguages: []lang.Language{lang.German, lang.French},
+		NoAutoDetect:    true,
+	}
+
+	cases := map[string]struct {
+		req  provider.Request
+		code serr.ErrorCode
+		ok   bool
+	}{
+		"supported":          {provider.Request{Text: []string{"hi"}, From: lang.English, To: lang.German}, 0, true},
+		"auto detect":        {provider.Request{Text: []string{"hi"}, To: lang.German}, serr.ErrInvalidLanguage, false},
+		"source":             {provider.Request{Text: []string{"hi"}, From: lang.Japanese, To: lang.German}, serr.ErrInvalidLanguage, false},
+		"target":             {provider.Request{Text: []string{"hi"}, From: lang.English, To: lang.Korean}, serr.ErrInvalidLanguage, false},
+		"glossary":           {provider.Request{Text: []string{"hi"}, From: lang.English, To: lang.German, GlossaryID: "g1"}, serr.ErrUnsupportedOption, false},
+		"formality":          {provider.Request{Text: []string{"hi"}, From: lang.English, To: lang.German, Options: provider.Options{Formality: provider.FormalityMore}}, serr.ErrUnsupportedOption, false},
+		"local file as text": {provider.Request{Binary: []byte(`{"@@locale": "en", "hi": "Hi"}`), FileName: "app.arb", From: lang.English, To: lang.French}, 0, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := &limitedClient{caps: caps}
+			_, err := Translate(context.Background(), tc.req, client)
+			if tc.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var terr *serr.TranslateError
+			if !errors.As(err, &terr) || terr.Code != tc.code {
+				t.Fatalf("expected code %d, got %v", tc.code, err)
+			}
+			if len(client.Requests) != 0 {
+				t.Errorf("request should be rejected before reaching the client")
+			}
+		})
+	}
+}
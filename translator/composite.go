@@ -0,0 +1,135 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// helpers shared by the clients composed of other clients (Fallback, Router)
+
+// jobs remembers which client accepted a document so status and result calls go to it
+type jobs struct {
+	clients sync.Map // document id -> provider.AsyncClient
+}
+
+func (j *jobs) add(res provider.AsyncResponse, c provider.AsyncClient) {
+	j.clients.Store(res.DocumentID, c)
+}
+
+func (j *jobs) client(op string, obj provider.AsyncResponse, name provider.Provider) (provider.AsyncClient, error) {
+	c, ok := j.clients.Load(obj.DocumentID)
+	if !ok {
+		return nil, serr.New(serr.ErrInvalidRequest, op, string(name), fmt.Errorf("unknown document %q", obj.DocumentID))
+	}
+	return c.(provider.AsyncClient), nil
+}
+
+func (j *jobs) checkStatus(ctx context.Context, obj provider.AsyncResponse, name provider.Provider) (provider.JobStatus, error) {
+	c, err := j.client("CheckStatus", obj, name)
+	if err != nil {
+		return provider.JobStatus{}, err
+	}
+	return c.CheckStatus(ctx, obj)
+}
+
+// getResult forgets the document once its result is downloaded and records the client that served it
+func (j *jobs) getResult(ctx context.Context, obj provider.AsyncResponse, name provider.Provider) (provider.Response, error) {
+	c, err := j.client("GetResult", obj, name)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	res, err := c.GetResult(ctx, obj)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	j.clients.Delete(obj.DocumentID)
+	res.Provider = c.Name()
+	return res, nil
+}
+
+// asyncComposite is implemented by the clients composed of other clients.
+// they implement provider.AsyncClient even when none of their clients translates documents
+type asyncComposite interface {
+	anyAsync() bool
+}
+
+// supportsAsync reports whether client translates documents, composites are asked through wrappers
+func supportsAsync(client provider.Client) bool {
+	if _, ok := client.(provider.AsyncClient); !ok {
+		return false
+	}
+	if c, ok := provider.As[asyncComposite](client); ok {
+		return c.anyAsync()
+	}
+	return true
+}
+
+// combineCapabilities returns what one of the clients supports,
+// with the smallest text limits so split requests fit every client
+func combineCapabilities(clients []provider.Client) provider.Capabilities {
+	var caps provider.Capabilities
+	var formats []sformat.Format
+	var source, target []lang.Language
+	anyFormat, anySource, anyTarget, anySize := false, false, false, false
+	caps.NoAutoDetect = len(clients) > 0
+
+	for _, c := range clients {
+		cc := c.Capabilities()
+		formats, anyFormat = union(formats, cc.Formats, anyFormat)
+		source, anySource = union(source, cc.SourceLanguages, anySource)
+		target, anyTarget = union(target, cc.TargetLanguages, anyTarget)
+		caps.MaxBatchSize = minLimit(caps.MaxBatchSize, cc.MaxBatchSize)
+		caps.MaxRequestSize = minLimit(caps.MaxRequestSize, cc.MaxRequestSize)
+		if cc.MaxDocumentSize == 0 {
+			anySize = true
+		}
+		caps.MaxDocumentSize = max(caps.MaxDocumentSize, cc.MaxDocumentSize)
+		caps.Glossaries = caps.Glossaries || cc.Glossaries
+		caps.Formality = caps.Formality || cc.Formality
+		caps.NoAutoDetect = caps.NoAutoDetect && cc.NoAutoDetect
+	}
+
+	if !anyFormat {
+		caps.Formats = formats
+	}
+	if !anySource {
+		caps.SourceLanguages = source
+	}
+	if !anyTarget {
+		caps.TargetLanguages = target
+	}
+	if anySize {
+		caps.MaxDocumentSize = 0
+	}
+	return caps
+}
+
+// union merges lists where an empty list means unrestricted
+func union[T comparable](list, add []T, unrestricted bool) ([]T, bool) {
+	if unrestricted || len(add) == 0 {
+		return nil, true
+	}
+	for _, v := range add {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list, false
+}
+
+func minLimit(a, b int) int {
+	if a == 0 {
+		return b
+	}
+	if b == 0 {
+		return a
+	}
+	return min(a, b)
+}
@@ -0,0 +1,120 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// FallbackProvider is the name of fallback clients, responses carry the name of the client that served them
+const FallbackProvider provider.Provider = "Fallback"
+
+// FallbackClient tries its clients in order, moving to the next one when a request fails with a retryable error (see serr.Retryable).
+// clients whose capabilities don't support a request are skipped
+type FallbackClient struct {
+	clients []provider.Client
+	jobs    jobs
+}
+
+// Fallback returns a client trying clients in order. ex: Fallback(deepl, google, libre)
+func Fallback(clients ...provider.Client) *FallbackClient {
+	return &FallbackClient{clients: clients}
+}
+
+func (f *FallbackClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	var errs []error
+	for _, c := range f.clients {
+		syncC, ok := c.(provider.SyncClient)
+		if !ok || provider.CheckCapabilities(req, requestType(req), c) != nil {
+			continue
+		}
+
+		res, err := syncC.Translate(ctx, req)
+		if err == nil {
+			res.Provider = c.Name()
+			return res, nil
+		}
+		errs = append(errs, err)
+		if !serr.Retryable(err) || ctx.Err() != nil {
+			return provider.Response{}, err
+		}
+	}
+	return provider.Response{}, f.failed("Translate", req, errs)
+}
+
+func (f *FallbackClient) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
+	var errs []error
+	for _, c := range f.clients {
+		asyncC, _ := c.(provider.AsyncClient)
+		if !supportsAsync(c) || provider.CheckCapabilities(req, sformat.File, c) != nil {
+			continue
+		}
+
+		res, err := asyncC.AsyncTranslate(ctx, req)
+		if err == nil {
+			f.jobs.add(res, asyncC)
+			return res, nil
+		}
+		errs = append(errs, err)
+		if !serr.Retryable(err) || ctx.Err() != nil {
+			return provider.AsyncResponse{}, err
+		}
+	}
+	return provider.AsyncResponse{}, f.failed("AsyncTranslate", req, errs)
+}
+
+func (f *FallbackClient) anyAsync() bool { return slices.ContainsFunc(f.clients, supportsAsync) }
+
+// failed returns the error of a request no client could serve, it keeps the code of the last error
+func (f *FallbackClient) failed(op string, req provider.Request, errs []error) error {
+	if len(errs) == 0 {
+		return serr.New(serr.ErrInvalidRequest, op, string(FallbackProvider), fmt.Errorf("no client supports %s -> %s", req.From, req.To))
+	}
+	code := serr.ErrProviderAPI
+	var terr *serr.TranslateError
+	if errors.As(errs[len(errs)-1], &terr) {
+		code = terr.Code
+	}
+	return serr.New(code, op, string(FallbackProvider), errors.Join(errs...))
+}
+
+func (f *FallbackClient) CheckStatus(ctx context.Context, obj provider.AsyncResponse) (provider.JobStatus, error) {
+	return f.jobs.checkStatus(ctx, obj, FallbackProvider)
+}
+
+func (f *FallbackClient) GetResult(ctx context.Context, obj provider.AsyncResponse) (provider.Response, error) {
+	return f.jobs.getResult(ctx, obj, FallbackProvider)
+}
+
+// GetCost estimates with the first client, the one serving requests when nothing fails
+func (f *FallbackClient) GetCost(req provider.Request) float32 {
+	if len(f.clients) == 0 {
+		return 0
+	}
+	return f.clients[0].GetCost(req)
+}
+
+func (f *FallbackClient) GetCharCount(req provider.Request) int {
+	if len(f.clients) == 0 {
+		return 0
+	}
+	return f.clients[0].GetCharCount(req)
+}
+
+// Capabilities combines the capabilities of the clients, see combineCapabilities
+func (f *FallbackClient) Capabilities() provider.Capabilities {
+	return combineCapabilities(f.clients)
+}
+
+func (f *FallbackClient) Name() provider.Provider {
+	return FallbackProvider
+}
+
+func (f *FallbackClient) Version() string {
+	return ""
+}
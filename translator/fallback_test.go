@@ -0,0 +1,207 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// scriptedClient is a fake.Upper with a name, capabilities and an error to fail with
+type scriptedClient struct {
+	fake.Upper
+	name provider.Provider
+	caps provider.Capabilities
+	err  error
+	docs map[string][]byte // async jobs
+}
+
+func (c *scriptedClient) Name() provider.Provider             { return c.name }
+func (c *scriptedClient) Capabilities() provider.Capabilities { return c.caps }
+
+func (c *scriptedClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	if c.err != nil {
+		c.Requests = append(c.Requests, req)
+		return provider.Response{}, c.err
+	}
+	return c.Upper.Translate(ctx, req)
+}
+
+func (c *scriptedClient) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
+	c.Requests = append(c.Requests, req)
+	if c.err != nil {
+		return provider.AsyncResponse{}, c.err
+	}
+	id := string(c.name) + "-doc"
+	c.docs = map[string][]byte{id: []byte(string(c.name) + ":" + string(req.Binary))}
+	return provider.AsyncResponse{DocumentID: id, DocumentKey: "key"}, nil
+}
+
+func (c *scriptedClient) CheckStatus(ctx context.Context, obj provider.AsyncResponse) (provider.JobStatus, error) {
+	_, ok := c.docs[obj.DocumentID]
+	return provider.JobStatus{Done: ok}, nil
+}
+
+func (c *scriptedClient) GetResult(ctx context.Context, obj provider.AsyncResponse) (provider.Response, error) {
+	return provider.Response{Binary: c.docs[obj.DocumentID]}, nil
+}
+
+func TestFallback(t *testing.T) {
+	quota := serr.New(serr.ErrQuotaExceeded, "Translate", "primary", nil)
+	outage := serr.New(serr.ErrHTTP, "Translate", "primary", &serr.HTTPError{StatusCode: 503})
+	badRequest := serr.New(serr.ErrHTTP, "Translate", "primary", &serr.HTTPError{StatusCode: 400})
+	timeout := serr.New(serr.ErrNetwork, "Translate", "primary", fmt.Errorf("Client.Timeout exceeded: %w", context.DeadlineExceeded))
+	anyLang := provider.Capabilities{}
+
+	cases := map[string]struct {
+		primary   provider.Capabilities
+		primErr   error
+		secErr    error
+		served    provider.Provider
+		code      serr.ErrorCode
+		secondary bool // whether the secondary client is called
+	}{
+		"primary serves":        {anyLang, nil, nil, "primary", 0, false},
+		"quota exceeded":        {anyLang, quota, nil, "secondary", 0, true},
+		"server error":          {anyLang, outage, nil, "secondary", 0, true},
+		"client timeout":        {anyLang, timeout, nil, "secondary", 0, true},
+		"not retryable":         {anyLang, badRequest, nil, "", serr.ErrHTTP, false},
+		"unsupported pair":      {provider.Capabilities{TargetLanguages: []lang.Language{lang.French}}, nil, nil, "secondary", 0, true},
+		"every client failing":  {anyLang, quota, outage, "", serr.ErrHTTP, true},
+		"no client supports it": {provider.Capabilities{NoAutoDetect: true}, nil, nil, "", serr.ErrInvalidLanguage, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			primary := &scriptedClient{name: "primary", caps: tc.primary, err: tc.primErr}
+			secondaryCaps := anyLang
+			if name == "no client supports it" {
+				secondaryCaps = provider.Capabilities{NoAutoDetect: true}
+			}
+			secondary := &scriptedClient{name: "secondary", caps: secondaryCaps, err: tc.secErr}
+
+			resp, err := Translate(context.Background(), provider.Request{Text: []string{"hello"}, To: lang.German}, Fallback(primary, secondary))
+			if tc.code != 0 {
+				var terr *serr.TranslateError
+				if !errors.As(err, &terr) || terr.Code != tc.code {
+					t.Fatalf("expected code %d, got %v", tc.code, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if resp.Provider != tc.served || resp.Text[0] != "HELLO" {
+				t.Errorf("got %+v, want served by %s", resp, tc.served)
+			}
+
+			if called := len(secondary.Requests) > 0; called != tc.secondary {
+				t.Errorf("secondary called: %v, want %v", called, tc.secondary)
+			}
+		})
+	}
+}
+
+func TestFallbackAsync(t *testing.T) {
+	primary := &scriptedClient{name: "primary", caps: provider.Capabilities{}, err: serr.New(serr.ErrNetwork, "AsyncTranslate", "primary", errors.New("connection reset"))}
+	secondary := &scriptedClient{name: "secondary", caps: provider.Capabilities{}}
+
+	resp, err := Translate(context.Background(), provider.Request{ReqType: format.File, Binary: []byte("%PDF-1.7"), FileName: "a.pdf", To: lang.German}, Fallback(primary, secondary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Binary) != "secondary:%PDF-1.7" || resp.Provider != "secondary" {
+		t.Errorf("document not served by the secondary client: %+v", resp)
+	}
+}
+
+func TestFallbackSyncOnly(t *testing.T) {
+	primary, secondary := &fake.Upper{}, &fake.Upper{}
+	client := Fallback(primary, secondary)
+
+	// none of the clients translates documents, so the docx is parsed locally
+	resp, err := Translate(context.Background(), provider.Request{ReqType: format.File, Binary: docx("hello"), FileName: "report.docx", To: lang.German}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(primary.Requests) != 1 || primary.Requests[0].ReqType != format.Text || len(resp.Binary) == 0 {
+		t.Errorf("expected the run text to be sent, got %+v", primary.Requests)
+	}
+
+	var terr *serr.TranslateError
+	_, err = Translate(context.Background(), provider.Request{ReqType: format.File, Binary: []byte("%PDF-1.7"), FileName: "a.pdf", To: lang.German}, client)
+	if !errors.As(err, &terr) || terr.Code != serr.ErrInvalidRequest {
+		t.Errorf("got %v, want an unsupported file translation", err)
+	}
+	if !supportsAsync(provider.Wrap(Fallback(primary, &scriptedClient{}), provider.Recovery(), nil)) {
+		t.Error("a fallback with an async client should translate documents")
+	}
+}
+
+func TestFallbackCallerDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	primary := &scriptedClient{name: "primary", err: serr.New(serr.ErrNetwork, "Translate", "primary", ctx.Err())}
+	secondary := &scriptedClient{name: "secondary"}
+	if _, err := Fallback(primary, secondary).Translate(ctx, provider.Request{Text: []string{"hello"}, To: lang.German}); err == nil {
+		t.Fatal("expected the deadline error")
+	}
+	if len(secondary.Requests) != 0 {
+		t.Error("the deadline of the caller should not fail over")
+	}
+}
+
+func TestFallbackCapabilities(t *testing.T) {
+	a := &scriptedClient{caps: provider.Capabilities{
+		Formats:         []format.Format{format.Text},
+		TargetLanguages: []lang.Language{lang.German},
+		MaxBatchSize:    50,
+		MaxDocumentSize: 10,
+		Glossaries:      true,
+		NoAutoDetect:    true,
+	}}
+	b := &scriptedClient{caps: provider.Capabilities{
+		Formats:         []format.Format{format.Text, format.File},
+		TargetLanguages: []lang.Language{lang.French},
+		MaxBatchSize:    10,
+		MaxDocumentSize: 20,
+	}}
+
+	caps := Fallback(a, b).Capabilities()
+	if !caps.SupportsFormat(format.File) || !caps.SupportsTarget(lang.German) || !caps.SupportsTarget(lang.French) || caps.SupportsTarget(lang.Japanese) {
+		t.Errorf("formats and languages should be combined: %+v", caps)
+	}
+	if caps.MaxBatchSize != 10 || caps.MaxDocumentSize != 20 || !caps.Glossaries || caps.NoAutoDetect {
+		t.Errorf("unexpected limits and features: %+v", caps)
+	}
+
+	b.caps.TargetLanguages = nil
+	if caps := Fallback(a, b).Capabilities(); !caps.SupportsTarget(lang.Japanese) {
+		t.Errorf("an unrestricted client makes the fallback unrestricted: %+v", caps)
+	}
+}
+
+func TestFallbackCircuitOpen(t *testing.T) {
+	anyLang := provider.Capabilities{}
+	down := &scriptedClient{name: "primary", caps: anyLang, err: serr.New(serr.ErrNetwork, "Translate", "primary", errors.New("timeout"))}
+	secondary := &scriptedClient{name: "secondary", caps: anyLang}
+	primary := provider.CircuitBreaking(provider.NewBreaker(1, time.Hour))(down)
+	client := Fallback(primary, secondary)
+
+	for range 3 {
+		resp, err := Translate(context.Background(), provider.Request{Text: []string{"hello"}, To: lang.German}, client)
+		if err != nil || resp.Provider != "secondary" {
+			t.Fatalf("got %+v %v", resp, err)
+		}
+	}
+	// the open circuit keeps the primary from being called again
+	if len(down.Requests) != 1 {
+		t.Errorf("primary called %d times", len(down.Requests))
+	}
+}
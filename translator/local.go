@@ -0,0 +1,75 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	provider "github.com/o0n1x/sublate-go/provider"
+
+	// built-in format handlers
+	_ "github.com/o0n1x/sublate-go/format/arb"
+	_ "github.com/o0n1x/sublate-go/format/epub"
+	_ "github.com/o0n1x/sublate-go/format/ooxml"
+)
+
+// translateLocal extracts the translatable segments of a file, translates them as a single text request and merges them back
+func translateLocal(ctx context.Context, req provider.Request, h sformat.Handler, client provider.SyncClient) (provider.Response, error) {
+	// handlers write the format they read
+	if out := req.Options.OutputFormat; out != "" && !slices.Contains(h.Extensions(), "."+strings.ToLower(strings.TrimPrefix(out, "."))) {
+		return provider.Response{}, serr.New(serr.ErrUnsupportedOption, "Translate", string(client.Name()), &provider.UnsupportedOptionError{Option: "OutputFormat", Value: out, Reason: "local pipelines can't convert documents"})
+	}
+	// the document options don't apply to the text request
+	req.Options.OutputFormat = ""
+	req.Options.MinifyDocument = false
+
+	segments, skeleton, err := h.Extract(req.Binary)
+	if err != nil {
+		return provider.Response{}, serr.New(serr.ErrInvalidFormat, "Translate", string(client.Name()), err)
+	}
+
+	var translated []string
+	// billing metadata of the text request is kept on the file response
+	response := provider.Response{FileName: req.FileName, Provider: client.Name()}
+	if len(segments) > 0 {
+		texts := make([]string, len(segments))
+		for i, s := range segments {
+			texts[i] = s.Text
+		}
+		// the text request keeps the options of the file request (glossary...)
+		textReq := req
+		textReq.ReqType = sformat.Text
+		textReq.Text = texts
+		textReq.Binary = nil
+		textReq.FileName = ""
+		if m, ok := h.(sformat.MarkupHandler); ok {
+			textReq.Options.TagHandling = provider.TagHandlingXML
+			textReq.Options.IgnoreTags = append(slices.Clone(textReq.Options.IgnoreTags), m.IgnoreTags()...)
+		}
+
+		res, err := translateSync(ctx, textReq, client)
+		if err != nil {
+			return provider.Response{}, err
+		}
+		if len(res.Text) != len(segments) {
+			return provider.Response{}, serr.New(serr.ErrInvalidResponse, "Translate", string(client.Name()), fmt.Errorf("got %d translations for %d segments", len(res.Text), len(segments)))
+		}
+		translated = res.Text
+		response.Segments = res.Segments
+		response.BilledCharacters = res.BilledCharacters
+		response.Model = res.Model
+		if res.Provider != "" {
+			response.Provider = res.Provider
+		}
+	}
+
+	out, err := h.Merge(skeleton, translated, req.To)
+	if err != nil {
+		return provider.Response{}, serr.New(serr.ErrInvalidFormat, "Translate", string(client.Name()), err)
+	}
+	response.Binary = out
+	return response, nil
+}
@@ -0,0 +1,142 @@
+package translator
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	format "github.com/o0n1x/sublate-go/format"
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+func TestTranslateLocalARB(t *testing.T) {
+	client := &fake.Upper{}
+	input := `{"@@locale": "en", "hello": "Hello {name}", "@hello": {"placeholders": {"name": {}}}}`
+
+	resp, err := Translate(context.Background(), provider.Request{
+		ReqType:  format.File,
+		Binary:   []byte(input),
+		FileName: "app_en.arb",
+		From:     lang.English,
+		To:       lang.German,
+	}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.Requests) != 1 || client.Requests[0].ReqType != format.Text {
+		t.Fatalf("expected a single text request, got %+v", client.Requests)
+	}
+	if got := client.Requests[0].Text; len(got) != 1 || got[0] != "Hello" {
+		t.Errorf("only literal text should be sent, got %q", got)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(resp.Binary, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["hello"] != "HELLO {name}" {
+		t.Errorf("got %q, want %q", doc["hello"], "HELLO {name}")
+	}
+	if doc["@@locale"] != "de" {
+		t.Errorf("got locale %q, want de", doc["@@locale"])
+	}
+	if resp.BilledCharacters != 5 || len(resp.Segments) != 1 || resp.Provider != "upper" {
+		t.Errorf("metadata not propagated: %+v", resp)
+	}
+}
+
+// docx returns a document with a single run of text
+func docx(text string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, _ := w.Create("word/document.xml")
+	f.Write([]byte(`<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:body></w:document>`))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestTranslateLocalOOXML(t *testing.T) {
+	client := &fake.Upper{}
+	resp, err := Translate(context.Background(), provider.Request{
+		ReqType:  format.File,
+		Binary:   docx("hello"),
+		FileName: "report.docx",
+		To:       lang.German,
+	}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.Requests) != 1 || client.Requests[0].Text[0] != "hello" {
+		t.Fatalf("expected the run text to be sent, got %+v", client.Requests)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(resp.Binary), int64(len(resp.Binary)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, _ := r.File[0].Open()
+	defer rc.Close()
+	var out bytes.Buffer
+	out.ReadFrom(rc)
+	if !strings.Contains(out.String(), "<w:t>HELLO</w:t>") {
+		t.Errorf("translation not merged: %s", out.String())
+	}
+}
+
+func TestTranslateDetectsFormat(t *testing.T) {
+	client := &fake.Upper{}
+	// no ReqType and no file name, the ARB content is sniffed
+	resp, err := Translate(context.Background(), provider.Request{
+		Binary: []byte(`{"@@locale": "en", "bye": "Goodbye"}`),
+		To:     lang.French,
+	}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp.Binary), `"bye": "GOODBYE"`) {
+		t.Errorf("file was not translated locally: %s", resp.Binary)
+	}
+	if resp.FileName != ".arb" {
+		t.Errorf("got file name %q, want the detected extension", resp.FileName)
+	}
+}
+
+func TestTranslateLocalEPUB(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range []struct{ name, content string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`},
+		{"content.opf", `<package><metadata><dc:title>Tom and Jerry</dc:title></metadata><manifest/><spine/></package>`},
+	} {
+		fw, _ := w.Create(f.name)
+		fw.Write([]byte(f.content))
+	}
+	w.Close()
+
+	client := &fake.Upper{}
+	_, err := Translate(context.Background(), provider.Request{
+		ReqType:  format.File,
+		Binary:   buf.Bytes(),
+		FileName: "book.epub",
+		To:       lang.German,
+		Options:  provider.Options{IgnoreTags: []string{"x"}},
+	}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.Requests) != 1 {
+		t.Fatalf("expected a single text request, got %+v", client.Requests)
+	}
+	req := client.Requests[0]
+	if req.Options.TagHandling != provider.TagHandlingXML || !slices.Contains(req.Options.IgnoreTags, "x") || !slices.Contains(req.Options.IgnoreTags, "code") {
+		t.Errorf("expected xml tag handling with the handler and request ignore tags, got %+v", req.Options)
+	}
+}
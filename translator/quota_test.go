@@ -0,0 +1,114 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"unicode/utf8"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// quotaClient is a fake.Upper reporting a fixed account usage
+type quotaClient struct {
+	fake.Upper
+	usage provider.Usage
+	err   error
+}
+
+func (c *quotaClient) Usage(context.Context) (provider.Usage, error) { return c.usage, c.err }
+
+func (c *quotaClient) GetCharCount(req provider.Request) int {
+	count := 0
+	for _, s := range req.Text {
+		count += utf8.RuneCountInString(s)
+	}
+	return count
+}
+
+func TestCheckQuota(t *testing.T) {
+	reqs := []provider.Request{
+		{ReqType: format.Text, Text: []string{"hello"}, To: lang.German},
+		{ReqType: format.Text, Text: []string{"world"}, To: lang.German},
+	}
+
+	cases := map[string]struct {
+		usage    provider.Usage
+		err      error
+		exceeded bool
+	}{
+		// the requests need 10 characters
+		"fits":          {provider.Usage{CharacterCount: 80, CharacterLimit: 100}, nil, false},
+		"exact":         {provider.Usage{CharacterCount: 90, CharacterLimit: 100}, nil, false},
+		"one over":      {provider.Usage{CharacterCount: 91, CharacterLimit: 100}, nil, true},
+		"over":          {provider.Usage{CharacterCount: 95, CharacterLimit: 100}, nil, true},
+		"no limit":      {provider.Usage{CharacterCount: 1_000_000}, nil, false},
+		"usage failure": {provider.Usage{}, errors.New("unreachable"), false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := &quotaClient{usage: tc.usage, err: tc.err}
+			err := CheckQuota(context.Background(), reqs, client)
+
+			var terr *serr.TranslateError
+			exceeded := errors.As(err, &terr) && terr.Code == serr.ErrQuotaExceeded
+			if exceeded != tc.exceeded {
+				t.Fatalf("got %v, exceeded want %v", err, tc.exceeded)
+			}
+
+			_, errs := BatchTranslate(context.Background(), reqs, client)
+			if tc.exceeded != (errs[0] != nil) {
+				t.Errorf("batch errors %v", errs)
+			}
+			if tc.exceeded && len(client.Requests) != 0 {
+				t.Errorf("batch should fail before translating, sent %d requests", len(client.Requests))
+			}
+		})
+	}
+}
+
+func TestCheckQuotaWrapped(t *testing.T) {
+	reqs := []provider.Request{{ReqType: format.Text, Text: []string{"hello"}, To: lang.German}}
+	inner := &quotaClient{usage: provider.Usage{CharacterCount: 99, CharacterLimit: 100}}
+	client := provider.Wrap(inner, provider.Chain(provider.Recovery(), provider.Validation()), nil)
+
+	var terr *serr.TranslateError
+	if err := CheckQuota(context.Background(), reqs, client); !errors.As(err, &terr) || terr.Code != serr.ErrQuotaExceeded {
+		t.Errorf("the usage of the wrapped client should be checked, got %v", err)
+	}
+}
+
+func TestCheckQuotaLocalFile(t *testing.T) {
+	// the file bytes are never sent, only the 11 characters of its message
+	reqs := []provider.Request{{
+		ReqType:  format.File,
+		Binary:   []byte(`{"@@locale": "en", "greeting": "Hello world", "@greeting": {"description": "shown on the home page"}}`),
+		FileName: "app_en.arb",
+		To:       lang.German,
+	}}
+
+	cases := map[string]struct {
+		usage    provider.Usage
+		exceeded bool
+	}{
+		"fits": {provider.Usage{CharacterCount: 89, CharacterLimit: 100}, false},
+		"over": {provider.Usage{CharacterCount: 90, CharacterLimit: 100}, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := &quotaClient{usage: tc.usage}
+			err := CheckQuota(context.Background(), reqs, client)
+
+			var terr *serr.TranslateError
+			if exceeded := errors.As(err, &terr) && terr.Code == serr.ErrQuotaExceeded; exceeded != tc.exceeded {
+				t.Errorf("got %v, exceeded want %v", err, tc.exceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	sformat "github.com/o0n1x/sublate-go/format"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// RouterProvider is the name of router clients, responses carry the name of the client that served them
+const RouterProvider provider.Provider = "Router"
+
+// Rule sends the requests it matches to one of its clients. empty fields match everything
+type Rule struct {
+	From     []lang.Language
+	To       []lang.Language
+	Type     sformat.Format                             // sformat.Text or sformat.File, "" for both
+	MinChars int                                        // see CharCount
+	MaxChars int                                        // 0 for no limit
+	Match    func(req provider.Request, chars int) bool // extra condition, nil matches everything
+
+	Clients []provider.Client // in order of preference
+}
+
+// Matches reports whether the request goes through the rule. chars is the result of CharCount
+func (r Rule) Matches(req provider.Request, chars int) bool {
+	if len(r.From) > 0 && !slices.Contains(r.From, req.From) {
+		return false
+	}
+	if len(r.To) > 0 && !slices.Contains(r.To, req.To) {
+		return false
+	}
+	if r.Type != "" && r.Type != requestType(req) {
+		return false
+	}
+	if chars < r.MinChars || (r.MaxChars > 0 && chars > r.MaxChars) {
+		return false
+	}
+	return r.Match == nil || r.Match(req, chars)
+}
+
+// CharCount is the length used by rules: the number of characters of the texts, or the size in bytes of a document
+func CharCount(req provider.Request) int {
+	if requestType(req) == sformat.File {
+		return len(req.Binary)
+	}
+	n := 0
+	for _, t := range req.Text {
+		n += utf8.RuneCountInString(t)
+	}
+	return n
+}
+
+type RouterOption func(*Router)
+
+// MinimizeCost makes the router pick the client of the matching rule with the lowest GetCost instead of the first one
+func MinimizeCost() RouterOption {
+	return func(r *Router) { r.minimizeCost = true }
+}
+
+// Router picks a client per request with the first rule that matches it and has a client supporting it.
+// add a rule without conditions last for a default client
+type Router struct {
+	rules        []Rule
+	minimizeCost bool
+	jobs         jobs
+}
+
+// NewRouter returns a client routing requests with rules. ex:
+//
+//	NewRouter([]Rule{
+//		{To: []lang.Language{lang.Japanese, lang.Korean}, Clients: []provider.Client{google}},
+//		{Clients: []provider.Client{deepl, libre}},
+//	}, MinimizeCost())
+func NewRouter(rules []Rule, opts ...RouterOption) *Router {
+	r := &Router{rules: rules}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Route returns the client serving req, reqType is the way it is sent (sformat.Text or sformat.File)
+func (r *Router) Route(req provider.Request, reqType sformat.Format) (provider.Client, error) {
+	chars := CharCount(req)
+	for _, rule := range r.rules {
+		if !rule.Matches(req, chars) {
+			continue
+		}
+		var best provider.Client
+		var bestCost float32
+		for _, c := range rule.Clients {
+			if !supports(c, req, reqType) {
+				continue
+			}
+			if !r.minimizeCost {
+				return c, nil
+			}
+			if cost := c.GetCost(req); best == nil || cost < bestCost {
+				best, bestCost = c, cost
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+	}
+	return nil, serr.New(serr.ErrInvalidRequest, "Route", string(RouterProvider), fmt.Errorf("no route for %s -> %s", req.From, req.To))
+}
+
+// supports reports whether c can serve req sent as reqType
+func supports(c provider.Client, req provider.Request, reqType sformat.Format) bool {
+	switch reqType {
+	case sformat.File:
+		if !supportsAsync(c) {
+			return false
+		}
+	default:
+		if _, ok := c.(provider.SyncClient); !ok {
+			return false
+		}
+	}
+	return provider.CheckCapabilities(req, reqType, c) == nil
+}
+
+func (r *Router) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	c, err := r.Route(req, requestType(req))
+	if err != nil {
+		return provider.Response{}, err
+	}
+	res, err := c.(provider.SyncClient).Translate(ctx, req)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	res.Provider = c.Name()
+	return res, nil
+}
+
+func (r *Router) AsyncTranslate(ctx context.Context, req provider.Request) (provider.AsyncResponse, error) {
+	c, err := r.Route(req, sformat.File)
+	if err != nil {
+		return provider.AsyncResponse{}, err
+	}
+	asyncC := c.(provider.AsyncClient)
+	res, err := asyncC.AsyncTranslate(ctx, req)
+	if err != nil {
+		return provider.AsyncResponse{}, err
+	}
+	r.jobs.add(res, asyncC)
+	return res, nil
+}
+
+func (r *Router) anyAsync() bool {
+	for _, rule := range r.rules {
+		if slices.ContainsFunc(rule.Clients, supportsAsync) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) CheckStatus(ctx context.Context, obj provider.AsyncResponse) (provider.JobStatus, error) {
+	return r.jobs.checkStatus(ctx, obj, RouterProvider)
+}
+
+func (r *Router) GetResult(ctx context.Context, obj provider.AsyncResponse) (provider.Response, error) {
+	return r.jobs.getResult(ctx, obj, RouterProvider)
+}
+
+// GetCost estimates with the client the request is routed to, 0 when there is none
+func (r *Router) GetCost(req provider.Request) float32 {
+	c, err := r.Route(req, requestType(req))
+	if err != nil {
+		return 0
+	}
+	return c.GetCost(req)
+}
+
+func (r *Router) GetCharCount(req provider.Request) int {
+	c, err := r.Route(req, requestType(req))
+	if err != nil {
+		return 0
+	}
+	return c.GetCharCount(req)
+}
+
+// Capabilities combines the capabilities of the clients of every rule, see combineCapabilities
+func (r *Router) Capabilities() provider.Capabilities {
+	var clients []provider.Client
+	for _, rule := range r.rules {
+		clients = append(clients, rule.Clients...)
+	}
+	return combineCapabilities(clients)
+}
+
+func (r *Router) Name() provider.Provider {
+	return RouterProvider
+}
+
+func (r *Router) Version() string {
+	return ""
+}
@@ -0,0 +1,138 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// pricedClient is a scriptedClient charging a fixed cost per request
+type pricedClient struct {
+	*scriptedClient
+	cost float32
+}
+
+func (c pricedClient) GetCost(provider.Request) float32 { return c.cost }
+
+func TestRouter(t *testing.T) {
+	anyLang := provider.Capabilities{}
+	asian := &scriptedClient{name: "asian", caps: anyLang}
+	short := &scriptedClient{name: "short", caps: anyLang}
+	german := &scriptedClient{name: "german", caps: provider.Capabilities{TargetLanguages: []lang.Language{lang.German}}}
+	general := &scriptedClient{name: "general", caps: anyLang}
+
+	router := NewRouter([]Rule{
+		{To: []lang.Language{lang.Japanese, lang.Korean}, Clients: []provider.Client{asian}},
+		{Type: format.Text, MaxChars: 5, Clients: []provider.Client{short}},
+		{Clients: []provider.Client{german, general}},
+	})
+
+	cases := map[string]struct {
+		req    provider.Request
+		served provider.Provider
+	}{
+		"language pair":              {provider.Request{Text: []string{"hello world"}, To: lang.Japanese}, "asian"},
+		"short text":                 {provider.Request{Text: []string{"hi"}, To: lang.German}, "short"},
+		"default rule":               {provider.Request{Text: []string{"hello world"}, To: lang.German}, "german"},
+		"first capable client":       {provider.Request{Text: []string{"hello world"}, To: lang.French}, "general"},
+		"characters not bytes":       {provider.Request{Text: []string{"héllo"}, To: lang.French}, "short"},
+		"texts are counted together": {provider.Request{Text: []string{"hel", "lo!"}, To: lang.French}, "general"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := Translate(context.Background(), tc.req, router)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Provider != tc.served || resp.Text[0] != strings.ToUpper(tc.req.Text[0]) {
+				t.Errorf("got %+v, want served by %s", resp, tc.served)
+			}
+		})
+	}
+}
+
+func TestRouterNoRoute(t *testing.T) {
+	router := NewRouter([]Rule{
+		{From: []lang.Language{lang.English}, Clients: []provider.Client{&scriptedClient{name: "english", caps: provider.Capabilities{}}}},
+	})
+	_, err := router.Translate(context.Background(), provider.Request{Text: []string{"hallo"}, From: lang.German, To: lang.French})
+	var terr *serr.TranslateError
+	if !errors.As(err, &terr) || terr.Code != serr.ErrInvalidRequest {
+		t.Fatalf("expected ErrInvalidRequest, got %v", err)
+	}
+	if cost := router.GetCost(provider.Request{Text: []string{"hallo"}, From: lang.German}); cost != 0 {
+		t.Errorf("unrouted requests cost nothing, got %v", cost)
+	}
+}
+
+func TestRouterMinimizeCost(t *testing.T) {
+	anyLang := provider.Capabilities{}
+	expensive := pricedClient{&scriptedClient{name: "expensive", caps: anyLang}, 20}
+	cheap := pricedClient{&scriptedClient{name: "cheap", caps: anyLang}, 5}
+	unsupported := pricedClient{&scriptedClient{name: "unsupported", caps: provider.Capabilities{TargetLanguages: []lang.Language{lang.French}}}, 1}
+	rules := []Rule{{Clients: []provider.Client{expensive, unsupported, cheap}}}
+	req := provider.Request{Text: []string{"hello"}, To: lang.German}
+
+	resp, err := Translate(context.Background(), req, NewRouter(rules, MinimizeCost()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "cheap" {
+		t.Errorf("expected the cheapest capable client, got %s", resp.Provider)
+	}
+	if cost := NewRouter(rules, MinimizeCost()).GetCost(req); cost != 5 {
+		t.Errorf("expected the cost of the routed client, got %v", cost)
+	}
+
+	resp, err = Translate(context.Background(), req, NewRouter(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "expensive" {
+		t.Errorf("without MinimizeCost the first client serves, got %s", resp.Provider)
+	}
+}
+
+func TestRouterAsync(t *testing.T) {
+	anyLang := provider.Capabilities{}
+	docs := &scriptedClient{name: "docs", caps: anyLang}
+	router := NewRouter([]Rule{
+		{Type: format.Text, Clients: []provider.Client{&scriptedClient{name: "texts", caps: anyLang}}},
+		{Type: format.File, Clients: []provider.Client{docs}},
+	})
+
+	resp, err := Translate(context.Background(), provider.Request{ReqType: format.File, Binary: []byte("%PDF-1.7"), FileName: "a.pdf", To: lang.German}, router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Binary) != "docs:%PDF-1.7" || resp.Provider != "docs" {
+		t.Errorf("document not served by the document client: %+v", resp)
+	}
+}
+
+func TestRouterSyncOnly(t *testing.T) {
+	texts := &fake.Upper{}
+	router := NewRouter([]Rule{{Clients: []provider.Client{texts}}})
+
+	// no route translates documents, so the docx is parsed locally
+	resp, err := Translate(context.Background(), provider.Request{ReqType: format.File, Binary: docx("hello"), FileName: "report.docx", To: lang.German}, router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts.Requests) != 1 || texts.Requests[0].ReqType != format.Text || resp.Provider != texts.Name() {
+		t.Errorf("expected the run text to be routed, got %+v", texts.Requests)
+	}
+
+	router = NewRouter([]Rule{{Clients: []provider.Client{texts, Fallback(&scriptedClient{})}}})
+	if !supportsAsync(router) {
+		t.Error("a router with an async client should translate documents")
+	}
+}
@@ -0,0 +1,112 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// ChunkError is the error of a sub-request of a split request
+type ChunkError struct {
+	Start int // index of the first text of the chunk in Request.Text
+	End   int // index after the last text
+	Err   error
+}
+
+func (e ChunkError) Error() string {
+	return fmt.Sprintf("texts [%d:%d]: %v", e.Start, e.End, e.Err)
+}
+
+// SplitError is returned, wrapped in an ErrPartialFailure TranslateError, when some sub-requests of a split request failed.
+// the response still holds the other translations, the texts of the failed chunks are left empty
+type SplitError struct {
+	Failed []ChunkError
+}
+
+func (e *SplitError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("%d chunks failed: %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+func (e *SplitError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// chunk is a range of texts fitting in a single request
+type chunk struct {
+	start, end int
+}
+
+// splitTexts groups texts in order into chunks that respect the limits. a text larger than MaxRequestSize gets a chunk of its own
+func splitTexts(texts []string, caps provider.Capabilities) []chunk {
+	var chunks []chunk
+	start, size := 0, 0
+	for i, t := range texts {
+		full := caps.MaxBatchSize > 0 && i-start >= caps.MaxBatchSize
+		tooBig := caps.MaxRequestSize > 0 && size+len(t) > caps.MaxRequestSize
+		if i > start && (full || tooBig) {
+			chunks = append(chunks, chunk{start, i})
+			start, size = i, 0
+		}
+		size += len(t)
+	}
+	return append(chunks, chunk{start, len(texts)})
+}
+
+// translateSplit translates a text request that is over the limits of the client as several requests and stitches the responses back in order
+func translateSplit(ctx context.Context, req provider.Request, chunks []chunk, client provider.SyncClient) (provider.Response, error) {
+	response := provider.Response{
+		Text:     make([]string, len(req.Text)),
+		Segments: make([]provider.SegmentMeta, len(req.Text)),
+		Provider: client.Name(),
+	}
+	var failed []ChunkError
+	reported := false
+	for _, c := range chunks {
+		sub := req
+		sub.Text = req.Text[c.start:c.end]
+
+		res, err := client.Translate(ctx, sub)
+		if err == nil && len(res.Text) != len(sub.Text) {
+			err = serr.New(serr.ErrInvalidResponse, "Translate", string(client.Name()), fmt.Errorf("got %d translations for %d texts", len(res.Text), len(sub.Text)))
+		}
+		if err != nil {
+			failed = append(failed, ChunkError{Start: c.start, End: c.end, Err: err})
+			continue
+		}
+
+		copy(response.Text[c.start:], res.Text)
+		copy(response.Segments[c.start:], res.Segments)
+		reported = reported || res.Segments != nil
+		response.BilledCharacters += res.BilledCharacters
+		if res.Model != "" {
+			response.Model = res.Model
+		}
+		if res.Provider != "" {
+			response.Provider = res.Provider
+		}
+	}
+
+	if !reported {
+		response.Segments = nil
+	}
+
+	switch len(failed) {
+	case 0:
+		return response, nil
+	case len(chunks):
+		return provider.Response{}, failed[0].Err
+	default:
+		return response, serr.New(serr.ErrPartialFailure, "Translate", string(client.Name()), &SplitError{Failed: failed})
+	}
+}
@@ -0,0 +1,105 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	serr "github.com/o0n1x/sublate-go/errors"
+	format "github.com/o0n1x/sublate-go/format"
+	fake "github.com/o0n1x/sublate-go/internal/fake"
+	lang "github.com/o0n1x/sublate-go/lang"
+	provider "github.com/o0n1x/sublate-go/provider"
+)
+
+// limitedClient is a fake.Upper with request limits that fails on texts containing "fail"
+type limitedClient struct {
+	fake.Upper
+	caps provider.Capabilities
+}
+
+func (c *limitedClient) Capabilities() provider.Capabilities { return c.caps }
+
+func (c *limitedClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	for _, s := range req.Text {
+		if strings.Contains(s, "fail") {
+			c.Requests = append(c.Requests, req)
+			return provider.Response{}, serr.New(serr.ErrHTTP, "Translate", "limited", errors.New("response code 500"))
+		}
+	}
+	return c.Upper.Translate(ctx, req)
+}
+
+func TestSplitTexts(t *testing.T) {
+	cases := map[string]struct {
+		texts []string
+		caps  provider.Capabilities
+		want  []chunk
+	}{
+		"no limits":   {[]string{"a", "b", "c"}, provider.Capabilities{}, []chunk{{0, 3}}},
+		"batch size":  {[]string{"a", "b", "c", "d", "e"}, provider.Capabilities{MaxBatchSize: 2}, []chunk{{0, 2}, {2, 4}, {4, 5}}},
+		"size":        {[]string{"aaaa", "bbbb", "cc", "dd"}, provider.Capabilities{MaxRequestSize: 6}, []chunk{{0, 1}, {1, 3}, {3, 4}}},
+		"oversized":   {[]string{"a", "bbbbbbbbbb", "c"}, provider.Capabilities{MaxRequestSize: 4}, []chunk{{0, 1}, {1, 2}, {2, 3}}},
+		"both limits": {[]string{"a", "b", "cccc", "d"}, provider.Capabilities{MaxBatchSize: 3, MaxRequestSize: 4}, []chunk{{0, 2}, {2, 3}, {3, 4}}},
+		"empty":       {nil, provider.Capabilities{MaxBatchSize: 2}, []chunk{{0, 0}}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := splitTexts(tc.texts, tc.caps); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTranslateSplit(t *testing.T) {
+	cases := map[string]struct {
+		texts  []string
+		want   []string
+		failed []ChunkError
+		code   serr.ErrorCode
+	}{
+		"all ok":      {[]string{"a", "b", "c", "d", "e"}, []string{"A", "B", "C", "D", "E"}, nil, 0},
+		"partial":     {[]string{"a", "b", "fail", "d", "e"}, []string{"A", "B", "", "", "E"}, []ChunkError{{Start: 2, End: 4}}, serr.ErrPartialFailure},
+		"all failing": {[]string{"fail", "fail"}, nil, nil, serr.ErrHTTP},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := &limitedClient{caps: provider.Capabilities{MaxBatchSize: 2}}
+			resp, err := Translate(context.Background(), provider.Request{ReqType: format.Text, Text: tc.texts, To: lang.German}, client)
+
+			if want := (len(tc.texts) + 1) / 2; len(client.Requests) != want {
+				t.Errorf("got %d requests, want %d", len(client.Requests), want)
+			}
+			if !reflect.DeepEqual(resp.Text, tc.want) {
+				t.Errorf("got %q, want %q", resp.Text, tc.want)
+			}
+			if tc.code == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(resp.Segments) != len(tc.texts) || resp.BilledCharacters != len(tc.texts) {
+					t.Errorf("metadata not stitched: %+v", resp)
+				}
+				return
+			}
+
+			var terr *serr.TranslateError
+			if !errors.As(err, &terr) || terr.Code != tc.code {
+				t.Fatalf("expected code %d, got %v", tc.code, err)
+			}
+			var serror *SplitError
+			if errors.As(err, &serror) {
+				if len(serror.Failed) != len(tc.failed) || serror.Failed[0].Start != tc.failed[0].Start || serror.Failed[0].End != tc.failed[0].End {
+					t.Errorf("got failed chunks %v, want %v", serror.Failed, tc.failed)
+				}
+			} else if tc.failed != nil {
+				t.Errorf("expected a SplitError, got %v", err)
+			}
+		})
+	}
+}
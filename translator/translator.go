@@ -2,7 +2,10 @@ package translator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sync"
 	"time"
 
 	serr "github.com/o0n1x/sublate-go/errors"
@@ -15,19 +18,45 @@ import (
 // Translate is the main entry point for all translations.
 // Handles async and sync providers  internally - always returns sync response.
 // Use this, not client.Translate() directly.
+// File requests are routed on the detected file type, a missing ReqType is inferred from the request.
 func Translate(ctx context.Context, req provider.Request, client provider.Client) (provider.Response, error) {
+	req.ReqType = requestType(req)
+
 	switch req.ReqType {
 	case sformat.File:
-		asyncC, ok := client.(provider.AsyncClient)
-		if !ok {
+		detected := sformat.Detect(req.FileName, req.Binary)
+		// providers guess the file type from the extension
+		if filepath.Ext(req.FileName) == "" && detected.Extension() != "" {
+			req.FileName += detected.Extension()
+		}
+
+		asyncC, _ := client.(provider.AsyncClient)
+		if h, ok := localHandler(detected, client); ok {
+			syncC, ok := client.(provider.SyncClient)
+			if !ok {
+				return provider.Response{}, serr.New(serr.ErrInvalidRequest, "Translate", "", fmt.Errorf("client does not support text translation"))
+			}
+			// only the text of the file goes through the provider
+			if err := provider.CheckCapabilities(req, sformat.Text, client); err != nil {
+				return provider.Response{}, err
+			}
+			return translateLocal(ctx, req, h, syncC)
+		}
+		if !supportsAsync(client) {
 			return provider.Response{}, serr.New(serr.ErrInvalidRequest, "Translate", "", fmt.Errorf("client does not support file translation"))
 		}
+		if err := provider.CheckCapabilities(req, sformat.File, client); err != nil {
+			return provider.Response{}, err
+		}
 		return translateAsyncComplete(ctx, req, asyncC)
 	case sformat.Text:
 		syncC, ok := client.(provider.SyncClient)
 		if !ok {
 			return provider.Response{}, serr.New(serr.ErrInvalidRequest, "Translate", "", fmt.Errorf("client does not support text translation"))
 		}
+		if err := provider.CheckCapabilities(req, sformat.Text, client); err != nil {
+			return provider.Response{}, err
+		}
 		return translateSync(ctx, req, syncC)
 	default:
 		return provider.Response{}, serr.New(serr.ErrInvalidRequest, "Translate", "", fmt.Errorf("invalid request type"))
@@ -35,28 +64,126 @@ func Translate(ctx context.Context, req provider.Request, client provider.Client
 	}
 }
 
+// requestType infers a missing ReqType from the request
+func requestType(req provider.Request) sformat.Format {
+	if req.ReqType != "" {
+		return req.ReqType
+	}
+	if len(req.Binary) > 0 {
+		return sformat.File
+	}
+	return sformat.Text
+}
+
+// localHandler returns the handler of a file type that is parsed locally so only its text goes through the provider (ARB, EPUB...).
+// documents like DOCX are only parsed locally when the client has no document endpoint, for composites when none of their clients has one
+func localHandler(d sformat.Descriptor, client provider.Client) (sformat.Handler, bool) {
+	h, ok := sformat.Lookup(d)
+	if !ok {
+		return nil, false
+	}
+	return h, !(d.Kind == sformat.KindDocument && supportsAsync(client))
+}
+
+// CheckQuota fails with ErrQuotaExceeded when the requests obviously don't fit in the remaining quota of the client.
+// the reporter is looked up under the middlewares of client with provider.As, clients without one always pass.
+// BatchTranslate runs it before translating, Translate doesn't to save a usage request per call
+func CheckQuota(ctx context.Context, reqs []provider.Request, client provider.Client) error {
+	reporter, ok := provider.As[provider.UsageReporter](client)
+	if !ok {
+		return nil
+	}
+	usage, err := reporter.Usage(ctx)
+	if err != nil {
+		return err
+	}
+
+	var chars, docs int64
+	for _, req := range reqs {
+		if requestType(req) != sformat.File {
+			chars += int64(client.GetCharCount(req))
+			continue
+		}
+		h, local := localHandler(sformat.Detect(req.FileName, req.Binary), client)
+		if !local {
+			chars += int64(client.GetCharCount(req))
+			docs++
+			continue
+		}
+		// only the segments of locally parsed files are sent. files that don't parse fail in Translate
+		if n, err := sformat.CharCount(h, req.Binary); err == nil {
+			chars += int64(n)
+		}
+	}
+
+	if remaining := usage.RemainingCharacters(); remaining >= 0 && chars > remaining {
+		return serr.New(serr.ErrQuotaExceeded, "CheckQuota", string(client.Name()), fmt.Errorf("%d characters requested, %d remaining", chars, remaining))
+	}
+	if remaining := usage.RemainingDocuments(); remaining >= 0 && docs > remaining {
+		return serr.New(serr.ErrQuotaExceeded, "CheckQuota", string(client.Name()), fmt.Errorf("%d documents requested, %d remaining", docs, remaining))
+	}
+	return nil
+}
+
+// BatchOption configures BatchTranslate
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	concurrency int
+}
+
+// WithConcurrency translates up to n requests at once. n <= 1 translates them one after the other, the default
+func WithConcurrency(n int) BatchOption {
+	return func(c *batchConfig) { c.concurrency = n }
+}
+
 // wrapper function that parralelizes translation based on the provider
 // by design this will wait for all batch to be completed and return all results/ errors even if its async
+// the batch fails fast with ErrQuotaExceeded when it doesn't fit in the quota of the client
 // TODO: make it possible to jst return async results and get status of the async results
-func BatchTranslate(ctx context.Context, req []provider.Request, client provider.Client) ([]provider.Response, []error) {
+func BatchTranslate(ctx context.Context, req []provider.Request, client provider.Client, opts ...BatchOption) ([]provider.Response, []error) {
 	var responses = make([]provider.Response, len(req))
 	var errs = make([]error, len(req))
 
-	for i, request := range req {
-		res, err := Translate(ctx, request, client)
-		if err != nil {
+	cfg := batchConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// usage lookup failures are not fatal, the provider still enforces its quota
+	var terr *serr.TranslateError
+	if err := CheckQuota(ctx, req, client); errors.As(err, &terr) && terr.Code == serr.ErrQuotaExceeded {
+		for i := range errs {
 			errs[i] = err
-			continue
 		}
-		responses[i] = res
+		return responses, errs
+	}
+
+	// each goroutine writes its own index
+	sem := make(chan struct{}, max(cfg.concurrency, 1))
+	var wg sync.WaitGroup
+	for i, request := range req {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			responses[i], errs[i] = Translate(ctx, request, client)
+		})
 	}
+	wg.Wait()
 	return responses, errs
 
 }
 
 //Functions with finer control
 
+// translateSync sends a text request, split into several requests when it's over the limits of the client
 func translateSync(ctx context.Context, req provider.Request, client provider.SyncClient) (provider.Response, error) {
+	if req.ReqType == sformat.Text {
+		if chunks := splitTexts(req.Text, client.Capabilities()); len(chunks) > 1 {
+			return translateSplit(ctx, req, chunks, client)
+		}
+	}
+
 	res, err := client.Translate(ctx, req)
 	if err != nil {
 		return provider.Response{}, err // all errors returned from deepl is wrapped as serr
@@ -92,6 +219,15 @@ func translateAsyncComplete(ctx context.Context, req provider.Request, client pr
 	if err != nil {
 		return provider.Response{}, err
 	}
+	if translation.FileName == "" {
+		translation.FileName = res.FileName
+	}
+	if translation.FileName == "" && req.Options.OutputFormat == "" {
+		translation.FileName = req.FileName
+	}
+	if translation.BilledCharacters == 0 {
+		translation.BilledCharacters = status.BilledCharacters
+	}
 
 	return translation, nil
 }
@@ -3,10 +3,14 @@ package translator
 import (
 	"context"
 	"os"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
 	format "github.com/o0n1x/sublate-go/format"
+	fake "github.com/o0n1x/sublate-go/internal/fake"
 	lang "github.com/o0n1x/sublate-go/lang"
 	provider "github.com/o0n1x/sublate-go/provider"
 	_ "github.com/o0n1x/sublate-go/provider/deepl"
@@ -19,7 +23,7 @@ func TestTranslatorIntegration(t *testing.T) {
 		t.Fatal("DEEPL_API_KEY not set")
 	}
 
-	client, err := provider.GetClient(provider.DeepL, apiKey)
+	client, err := provider.GetClient(provider.DeepL, provider.WithAPIKey(apiKey))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -66,7 +70,7 @@ func TestBatchTranslatorIntegration(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	client, err := provider.GetClient(provider.DeepL, apiKey)
+	client, err := provider.GetClient(provider.DeepL, provider.WithAPIKey(apiKey))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,3 +108,69 @@ func TestBatchTranslatorIntegration(t *testing.T) {
 		}
 	}
 }
+
+// gatedClient records how many requests it translates at once, requests block until release is closed
+type gatedClient struct {
+	fake.Upper
+	arrived        chan struct{}
+	release        chan struct{}
+	inFlight, peak atomic.Int32
+}
+
+func (c *gatedClient) Translate(ctx context.Context, req provider.Request) (provider.Response, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for p := c.peak.Load(); n > p && !c.peak.CompareAndSwap(p, n); p = c.peak.Load() {
+	}
+	c.arrived <- struct{}{}
+	<-c.release
+	return provider.Response{Text: req.Text, Provider: c.Name()}, nil
+}
+
+func TestBatchTranslateConcurrency(t *testing.T) {
+	reqs := make([]provider.Request, 6)
+	for i := range reqs {
+		reqs[i] = provider.Request{ReqType: format.Text, Text: []string{strconv.Itoa(i)}, To: lang.German}
+	}
+
+	cases := map[string]struct {
+		opts  []BatchOption
+		limit int
+	}{
+		"sequential": {nil, 1},
+		"limited":    {[]BatchOption{WithConcurrency(3)}, 3},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := &gatedClient{arrived: make(chan struct{}, len(reqs)), release: make(chan struct{})}
+			done := make(chan struct{})
+			var responses []provider.Response
+			var errs []error
+			go func() {
+				defer close(done)
+				responses, errs = BatchTranslate(context.Background(), reqs, client, tc.opts...)
+			}()
+
+			// limit requests have to be in flight together before any of them is released
+			for range tc.limit {
+				select {
+				case <-client.arrived:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("expected %d requests at once, got %d", tc.limit, client.inFlight.Load())
+				}
+			}
+			close(client.release)
+			<-done
+
+			for i := range reqs {
+				if errs[i] != nil || responses[i].Text[0] != strconv.Itoa(i) {
+					t.Errorf("request %d: got %v, %v", i, responses[i].Text, errs[i])
+				}
+			}
+			if peak := client.peak.Load(); peak > int32(tc.limit) {
+				t.Errorf("got %d requests at once, want at most %d", peak, tc.limit)
+			}
+		})
+	}
+}